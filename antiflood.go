@@ -0,0 +1,202 @@
+package easytgbot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FloodAction is what AntiFlood does with a flooding user.
+type FloodAction int
+
+// Flood actions. The flooding update never reaches the handler.
+const (
+	// FloodIgnore drops the update.
+	FloodIgnore FloodAction = iota
+	// FloodWarn replies with AntiFloodOptions.Warning once per window.
+	FloodWarn
+	// FloodDelete deletes the message.
+	FloodDelete
+	// FloodMute deletes the message and mutes the user for MuteDuration.
+	FloodMute
+	// FloodBan deletes the message and bans the user.
+	FloodBan
+)
+
+// AntiFloodOptions configures the anti-flood middleware.
+type AntiFloodOptions struct {
+	// Limit is how many messages a user may send per Window. Default: 5
+	Limit int
+	// Window is the sliding window. Default: 10s
+	Window time.Duration
+
+	Action FloodAction
+
+	// MuteDuration for FloodMute. Default: 10m
+	MuteDuration string
+	// Warning for FloodWarn. Default: "Please slow down."
+	Warning string
+
+	// ExemptAdmins skips chat administrators.
+	ExemptAdmins bool
+	// AdminTTL is how long administrator lists are cached. Default: 10m
+	AdminTTL time.Duration
+
+	// Thresholds overrides Limit and Window per chat; zero values keep
+	// the defaults.
+	Thresholds func(chatID int64) (int, time.Duration)
+}
+
+type floodUser struct {
+	times  []time.Time
+	warned time.Time
+	window time.Duration
+}
+
+type floodAdmins struct {
+	ids     map[int64]bool
+	expires time.Time
+}
+
+// AntiFlood returns a middleware for Bot.UseAll that limits how many
+// messages each user may send per chat, whether handled or not.
+func AntiFlood(opts AntiFloodOptions) MiddlewareFunc {
+	if opts.Limit <= 0 {
+		opts.Limit = 5
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.MuteDuration == "" {
+		opts.MuteDuration = "10m"
+	}
+	if opts.Warning == "" {
+		opts.Warning = "Please slow down."
+	}
+	if opts.AdminTTL <= 0 {
+		opts.AdminTTL = 10 * time.Minute
+	}
+
+	var (
+		mutex  sync.Mutex
+		users  = make(map[string]*floodUser)
+		admins = make(map[int64]*floodAdmins)
+		swept  = time.Now()
+	)
+
+	// flooding records a message and reports whether the user exceeds
+	// the limit and whether they were already warned in this window.
+	flooding := func(chatID int64, userID int64, now time.Time) (bool, bool) {
+		limit, window := opts.Limit, opts.Window
+		if opts.Thresholds != nil {
+			if l, w := opts.Thresholds(chatID); l > 0 {
+				limit = l
+				if w > 0 {
+					window = w
+				}
+			} else if w > 0 {
+				window = w
+			}
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if now.Sub(swept) > opts.Window {
+			for key, user := range users {
+				if len(user.times) == 0 || now.Sub(user.times[len(user.times)-1]) > user.window {
+					delete(users, key)
+				}
+			}
+			swept = now
+		}
+
+		key := fmt.Sprintf("%d:%d", chatID, userID)
+		user, ok := users[key]
+		if !ok {
+			user = &floodUser{}
+			users[key] = user
+		}
+		user.window = window
+		times := user.times[:0]
+		for _, t := range user.times {
+			if now.Sub(t) < window {
+				times = append(times, t)
+			}
+		}
+		user.times = append(times, now)
+		if len(user.times) <= limit {
+			return false, false
+		}
+		warned := now.Sub(user.warned) < window
+		user.warned = now
+		return true, warned
+	}
+
+	isAdmin := func(bot *Bot, chatID int64, userID int64) bool {
+		mutex.Lock()
+		cached, ok := admins[chatID]
+		mutex.Unlock()
+		if !ok || time.Now().After(cached.expires) {
+			res, err := bot.GetChatAdministrators(chatID)
+			if err != nil {
+				bot.logger.Warn("anti flood: get administrators", "chat_id", chatID, "error", err)
+				return false
+			}
+			cached = &floodAdmins{
+				ids:     make(map[int64]bool),
+				expires: time.Now().Add(opts.AdminTTL),
+			}
+			for _, member := range res.Array() {
+				cached.ids[member.Get("user.id").Int()] = true
+			}
+			mutex.Lock()
+			admins[chatID] = cached
+			mutex.Unlock()
+		}
+		return cached.ids[userID]
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			message := update.Get("message")
+			chatID := message.Get("chat.id").Int()
+			userID := message.Get("from.id").Int()
+			// the parts of an album were counted on their own
+			if !message.Exists() || chatID == 0 || userID == 0 || update.Get("media_group").Exists() {
+				return next(context, bot, update)
+			}
+			flood, warned := flooding(chatID, userID, time.Now())
+			if !flood {
+				return next(context, bot, update)
+			}
+			group := message.Get("chat.type").String() != "private"
+			if group && opts.ExemptAdmins && isAdmin(bot, chatID, userID) {
+				return next(context, bot, update)
+			}
+
+			var err error
+			switch opts.Action {
+			case FloodWarn:
+				if !warned {
+					return update.Reply(opts.Warning, nil)
+				}
+			case FloodDelete:
+				_, err = bot.DeleteMessage(chatID, message.Get("message_id").Int())
+			case FloodMute, FloodBan:
+				if !group {
+					break
+				}
+				bot.DeleteMessage(chatID, message.Get("message_id").Int())
+				if opts.Action == FloodMute {
+					_, err = bot.Mute(chatID, userID, opts.MuteDuration)
+				} else {
+					_, err = bot.Ban(chatID, userID)
+				}
+			}
+			if err != nil {
+				bot.logger.Warn("anti flood: action failed", "chat_id", chatID, "user_id", userID, "error", err)
+			}
+			return JSONBody{}
+		}
+	}
+}
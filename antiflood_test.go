@@ -0,0 +1,95 @@
+package easytgbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAntiFlood(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		if method == "getChatAdministrators" {
+			return []JSONBody{{"status": "creator", "user": JSONBody{"id": 1}}}, nil
+		}
+		return true, nil
+	}
+	bot.UseAll(AntiFlood(AntiFloodOptions{
+		Limit:        2,
+		Window:       time.Minute,
+		Action:       FloodMute,
+		ExemptAdmins: true,
+	}))
+	handled := 0
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		handled++
+		return JSONBody{}
+	})
+
+	send := func(userID string) {
+		bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"from":{"id":`+userID+`},"chat":{"id":-100,"type":"supergroup"},"text":"spam"}}`))
+	}
+	for i := 0; i < 3; i++ {
+		send("7")
+		send("1")
+	}
+	if handled != 5 {
+		t.Errorf("handled %d messages, want 5", handled)
+	}
+
+	methods := []string{}
+	for _, call := range api.Calls() {
+		methods = append(methods, call.Method)
+	}
+	want := []string{"getChatAdministrators", "deleteMessage", "restrictChatMember"}
+	if len(methods) != len(want) {
+		t.Fatalf("unexpected calls %v", methods)
+	}
+	for i := range want {
+		if methods[i] != want[i] {
+			t.Errorf("unexpected calls %v, want %v", methods, want)
+		}
+	}
+}
+
+func TestAntiFloodScene(t *testing.T) {
+	bot, _ := newTestBot(t, Settings{MediaGroupWait: time.Hour})
+	bot.UseAll(AntiFlood(AntiFloodOptions{Limit: 2, Window: time.Minute}))
+	steps := 0
+	step := func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+		steps++
+		state.Stay()
+		return JSONBody{}
+	}
+	bot.Scene("chat", step)
+	if _, err := bot.EnterScene(nil, sceneUpdate("go"), "chat"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		bot.ApplyHandlers(nil, sceneUpdate("spam"))
+	}
+	// entering ran one step without a flood check, then two passed
+	if steps != 3 {
+		t.Errorf("scene ran %d steps, want 3", steps)
+	}
+
+	// album parts count too, and are dropped from the album
+	for i := 0; i < 2; i++ {
+		bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"from":{"id":8},"chat":{"id":8,"type":"private"},"media_group_id":"g","photo":[{"file_id":"f"}]}}`))
+	}
+	bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":3,"from":{"id":8},"chat":{"id":8,"type":"private"},"text":"spam"}}`))
+	bot.mediaGroupMutex.Lock()
+	parts := len(bot.mediaGroups["8:g"].updates)
+	bot.mediaGroupMutex.Unlock()
+	if parts != 2 {
+		t.Errorf("album has %d parts, want 2", parts)
+	}
+	if _, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":4,"from":{"id":8},"chat":{"id":8,"type":"private"},"media_group_id":"g","photo":[{"file_id":"f"}]}}`)); err != nil {
+		t.Fatal(err)
+	}
+	bot.mediaGroupMutex.Lock()
+	parts = len(bot.mediaGroups["8:g"].updates)
+	bot.mediaGroupMutex.Unlock()
+	if parts != 2 {
+		t.Errorf("flooding album part was buffered, album has %d parts", parts)
+	}
+}
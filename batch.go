@@ -0,0 +1,88 @@
+package easytgbot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// batchKey holds the actions of a batch result.
+const batchKey = "\x00batch"
+
+// Batch returns a handler result made of several actions run in
+// order, e.g. answering a callback query, editing its message and
+// notifying an admin. Actions without a method are skipped and nested
+// batches are flattened.
+//
+// In webhook mode the first action that fits a webhook reply becomes
+// the reply and the others are sent through the API. Telegram runs the
+// reply after the response is written, so it lands after them.
+func Batch(actions ...JSONBody) JSONBody {
+	return JSONBody{batchKey: actions}
+}
+
+// Actions returns the actions of a handler result in order.
+func Actions(res JSONBody) []JSONBody {
+	batch, ok := res[batchKey].([]JSONBody)
+	if !ok {
+		if method, _ := res["method"].(string); method != "" {
+			return []JSONBody{res}
+		}
+		return nil
+	}
+	actions := []JSONBody{}
+	for _, action := range batch {
+		actions = append(actions, Actions(action)...)
+	}
+	return actions
+}
+
+// ActionError is the failure of one action of a batch.
+type ActionError struct {
+	Index  int
+	Method string
+	Err    error
+}
+
+func (e ActionError) Error() string {
+	return fmt.Sprintf("action %d (%s): %s", e.Index, e.Method, e.Err)
+}
+
+func (e ActionError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError lists the failed actions of a batch; the others ran.
+type BatchError []ActionError
+
+func (e BatchError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// sendActions sends actions through the API in order, skipping the
+// one at index skip, and returns the last result.
+func (bot *Bot) sendActions(actions []JSONBody, skip int) (Update, error) {
+	var (
+		res    Update
+		failed BatchError
+	)
+	for i, action := range actions {
+		if i == skip {
+			continue
+		}
+		method, params := splitMethod(action)
+		result, err := bot.MakeRequest(method, params)
+		if err != nil {
+			failed = append(failed, ActionError{Index: i, Method: method, Err: err})
+			continue
+		}
+		res = result
+	}
+	if len(failed) > 0 {
+		return res, failed
+	}
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package easytgbot
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		if params["chat_id"] == float64(99) {
+			return nil, &Error{Code: 400, Message: "Bad Request: chat not found"}
+		}
+		return true, nil
+	}
+	update := NewUpdate(`{"callback_query":{"id":"5","from":{"id":7},"data":"x","message":{"message_id":3,"chat":{"id":7,"type":"private"}}}}`)
+	res := Batch(
+		update.AnswerCallbackQuery("done", nil),
+		JSONBody{},
+		Batch(
+			update.EditMessageText("edited", nil),
+			JSONBody{"method": "sendMessage", "chat_id": 99, "text": "admin"},
+		),
+	)
+	if actions := Actions(res); len(actions) != 3 {
+		t.Fatalf("got %d actions", len(actions))
+	}
+
+	w := httptest.NewRecorder()
+	err := bot.Respond(w, res)
+	var batchErr BatchError
+	if !errors.As(err, &batchErr) || len(batchErr) != 1 || batchErr[0].Index != 2 || batchErr[0].Method != "sendMessage" {
+		t.Fatalf("got error %v", err)
+	}
+	if ClassifyError(batchErr[0]) != ChatNotFound {
+		t.Errorf("batch error does not unwrap: %v", err)
+	}
+	if !strings.Contains(w.Body.String(), `"method":"answerCallbackQuery"`) {
+		t.Errorf("got reply %s", w.Body)
+	}
+	calls := api.Calls()
+	if len(calls) != 2 || calls[0].Method != "editMessageText" || calls[1].Method != "sendMessage" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	// polling sends every action in order
+	if err := bot.Respond(nil, res); err == nil {
+		t.Error("expected the failed action to be reported")
+	}
+	calls = api.Calls()[2:]
+	if len(calls) != 3 || calls[0].Method != "answerCallbackQuery" || calls[1].Method != "editMessageText" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
@@ -1,13 +1,16 @@
 package easytgbot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/url"
 	"regexp"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/imroc/req"
@@ -53,11 +56,49 @@ type Bot struct {
 	Timeout time.Duration
 	Self    Update
 
-	handlers        map[string]interface{}
+	// MediaGroupWait collects albums into one update, see Settings
+	MediaGroupWait time.Duration
+	// SplitDocumentThreshold sends long texts as a file, see Settings
+	SplitDocumentThreshold int
+
+	handlers        map[string][]*route
+	actions         []string
+	inlines         []string
 	client          *req.Req
 	shutdownChannel chan interface{}
+	shutdownOnce    sync.Once
 	apiEndpoint     string
 	middleware      []MiddlewareFunc
+	middlewareAll   []MiddlewareFunc
+
+	scenes      map[string]*Scene
+	sceneStates map[string]*SceneState
+	sceneMutex  sync.Mutex
+	sceneSwept  time.Time
+
+	sessions     map[string]*Session
+	sessionMutex sync.Mutex
+
+	callbackCodec *CallbackCodec
+
+	mediaGroups     map[string]*mediaGroup
+	mediaGroupMutex sync.Mutex
+
+	menus      map[string]*Menu
+	menuStacks map[string]*menuStack
+	menuSwept  time.Time
+	menuMutex  sync.Mutex
+
+	errorHandlers []func(error, Update)
+	errorMutex    sync.Mutex
+
+	logger   Logger
+	redactor redactor
+	metrics  *Metrics
+
+	beforeHooks []BeforeRequestFunc
+	afterHooks  []AfterRequestFunc
+	hookMutex   sync.RWMutex
 }
 
 // Settings represents a utility struct for passing certain
@@ -83,6 +124,29 @@ type Settings struct {
 	Proxy string
 
 	GetMe bool
+
+	// CallbackSecret signs callback_data, see CallbackCodec
+	CallbackSecret string
+
+	// CallbackStore keeps callback_data over 64 bytes. Default: in memory
+	CallbackStore SessionStore
+
+	// SplitDocumentThreshold sends texts needing more chunks than this
+	// as a text file instead. Default: 0, always send chunks
+	SplitDocumentThreshold int
+
+	// Logger receives the logs of the bot, with the token redacted.
+	// Default: the standard logger, with debug messages if Debug is set
+	Logger Logger
+
+	// MediaGroupWait is how long to wait for more messages of an album
+	// before handling them as one update, e.g. time.Second. The album
+	// goes to the "media_group" handler, or the handler of its first
+	// message, with a nil context since the requests that brought its
+	// messages are over; results are sent through the API. Albums
+	// still collecting when the bot stops are dropped.
+	// Default: 0, every message is handled on its own
+	MediaGroupWait time.Duration
 }
 
 // Update is a response from the Telegram API with the result stored raw.
@@ -123,6 +187,9 @@ func (t Update) Map() map[string]Update {
 	return res
 }
 
+// ErrUnhandled is returned by ApplyHandlers for updates without a handler.
+var ErrUnhandled = errors.New("unsupported update type")
+
 // Error is an error containing extra information returned by the Telegram API.
 type Error struct {
 	Code       int64
@@ -166,11 +233,31 @@ func New(token string, opts Settings) (*Bot, error) {
 		Buffer:  opts.Updates,
 		Timeout: opts.Timeout,
 
+		MediaGroupWait:         opts.MediaGroupWait,
+		SplitDocumentThreshold: opts.SplitDocumentThreshold,
+
 		client:      client,
 		apiEndpoint: opts.Endpoint,
-		handlers:    make(map[string]interface{}),
+		handlers:    make(map[string][]*route),
+
+		shutdownChannel: make(chan interface{}),
+		scenes:          make(map[string]*Scene),
+		sceneStates:     make(map[string]*SceneState),
+		sessions:        make(map[string]*Session),
+
+		callbackCodec: NewCallbackCodec(opts.CallbackSecret, opts.CallbackStore),
+		mediaGroups:   make(map[string]*mediaGroup),
+		menus:         make(map[string]*Menu),
+		menuStacks:    make(map[string]*menuStack),
+		metrics:       newMetrics(),
 	}
 
+	if opts.Logger == nil {
+		opts.Logger = NewStdLogger(nil, bot.Debug)
+	}
+	bot.redactor = newRedactor(token)
+	bot.logger = redactLogger{next: opts.Logger, redactor: bot.redactor}
+
 	if opts.GetMe {
 		self, err := bot.GetMe()
 		if err != nil {
@@ -184,8 +271,50 @@ func New(token string, opts Settings) (*Bot, error) {
 }
 
 // MakeRequest makes a request to a specific endpoint with our token.
+//
+// Texts and captions over MessageLimit and CaptionLimit are split
+// and sent in order, see SplitText. Every call sent runs the
+// BeforeRequest and AfterRequest hooks.
 func (bot *Bot) MakeRequest(endpoint string, params JSONBody) (Update, error) {
+	return bot.makeRequest(context.Background(), endpoint, params)
+}
+
+// makeRequest is MakeRequest bounded by ctx as well as bot.Timeout.
+func (bot *Bot) makeRequest(ctx context.Context, endpoint string, params JSONBody) (Update, error) {
+	send := func(method string, params JSONBody) (Update, error) {
+		return bot.makeRequest(ctx, method, params)
+	}
+	if res, ok, err := bot.splitRequest(endpoint, params, send); ok {
+		return res, err
+	}
+
+	sent, sentParams, res, err := bot.beforeRequest(endpoint, params)
+	if res == nil && err == nil && oversized(sent, sentParams) {
+		// the hooks made it too long, split it without running them
+		// again on the parts
+		result, _, err := bot.splitRequest(sent, sentParams, func(method string, params JSONBody) (Update, error) {
+			res, err := bot.doRequest(ctx, method, params)
+			bot.afterRequest(method, params, res, err)
+			return res, err
+		})
+		return result, err
+	}
+	if res == nil && err == nil {
+		var result Update
+		result, err = bot.doRequest(ctx, sent, sentParams)
+		res = &result
+	}
+	if res == nil {
+		res = &Update{}
+	}
+	bot.afterRequest(sent, sentParams, *res, err)
+	return *res, err
+}
+
+// doRequest sends a request to the Bot API.
+func (bot *Bot) doRequest(ctx context.Context, endpoint string, params JSONBody) (Update, error) {
 	method := fmt.Sprintf(bot.apiEndpoint, bot.Token, endpoint)
+	start := time.Now()
 	var jsonBody JSONBody
 	if params == nil {
 		jsonBody = JSONBody{}
@@ -193,19 +322,24 @@ func (bot *Bot) MakeRequest(endpoint string, params JSONBody) (Update, error) {
 		jsonBody = params
 	}
 
-	// set timeout
-	bot.client.SetTimeout(bot.Timeout)
 	// post data
 	var (
 		resp *req.Resp
 		err  error
 	)
 
+	// the timeout applies per request, so changes to Timeout take effect
+	if bot.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bot.Timeout)
+		defer cancel()
+	}
+
 	header := req.Header{
 		"User-Agent": UserAgent,
 	}
 
-	if endpoint == "setWebhook" {
+	if endpoint == "setWebhook" || hasFileUpload(params) {
 		var fileUploads []req.FileUpload
 		fromParams := url.Values{}
 		for key, value := range params {
@@ -216,30 +350,38 @@ func (bot *Bot) MakeRequest(endpoint string, params JSONBody) (Update, error) {
 				fromParams.Add(key, fmt.Sprintf("[\"%v\"]", strings.Join(value.([]string), "\",\"")))
 			case req.FileUpload:
 				fileUploads = append(fileUploads, value.(req.FileUpload))
+			default:
+				data, _ := json.Marshal(value)
+				fromParams.Add(key, string(data))
 			}
 		}
-		resp, err = bot.client.Post(method, header, fromParams, fileUploads)
+		resp, err = bot.client.Post(method, header, fromParams, fileUploads, ctx)
 	} else {
-		resp, err = bot.client.Post(method, header, req.BodyJSON(&jsonBody))
+		resp, err = bot.client.Post(method, header, req.BodyJSON(&jsonBody), ctx)
 	}
 
 	if err != nil {
+		err = bot.redactor.Error(err)
+		bot.metrics.observeRequest(endpoint, "error", time.Since(start))
+		bot.logger.Error("api request failed", "method", endpoint, "chat_id", params["chat_id"], "latency", time.Since(start), "error", err)
 		return Update{}, err
 	}
-	if bot.Debug {
-		log.Printf("method: %s, resp: %-v", method, resp)
-	}
 	data, _ := resp.ToString()
 	apiJSON := Update{gjson.Parse(data)}
 	ok := apiJSON.Get("ok").Bool()
 	if !ok {
 		// error
-		return apiJSON, &Error{
+		apiErr := &Error{
 			Code:       apiJSON.Get("error_code").Int(),
-			Message:    apiJSON.Get("description").String(),
+			Message:    bot.redactor.String(apiJSON.Get("description").String()),
 			Parameters: apiJSON.Get("parameters"),
 		}
+		bot.metrics.observeRequest(endpoint, strconv.FormatInt(apiErr.Code, 10), time.Since(start))
+		bot.logger.Warn("api error", "method", endpoint, "chat_id", params["chat_id"], "latency", time.Since(start), "error_code", apiErr.Code, "error", apiErr.Message)
+		return apiJSON, apiErr
 	}
+	bot.metrics.observeRequest(endpoint, "200", time.Since(start))
+	bot.logger.Debug("api request", "method", endpoint, "chat_id", params["chat_id"], "latency", time.Since(start))
 
 	result := apiJSON.Get("result")
 
@@ -276,6 +418,7 @@ func (bot *Bot) GetUpdates(params JSONBody) (chan Update, error) {
 	bot.DeleteWebhook()
 
 	ch := make(chan Update, bot.Buffer)
+	bot.metrics.setQueue(ch)
 	offset, _ := strconv.ParseInt(strconv.Itoa(params["offset"].(int)), 10, 64)
 
 	go func() {
@@ -289,8 +432,7 @@ func (bot *Bot) GetUpdates(params JSONBody) (chan Update, error) {
 
 			resp, err := bot.MakeRequest("getUpdates", params)
 			if err != nil {
-				log.Println(err)
-				log.Println("Failed to get updates, retrying in 3 seconds...")
+				bot.logger.Warn("failed to get updates, retrying in 3 seconds", "error", err)
 				time.Sleep(time.Second * 3)
 
 				continue
@@ -326,6 +468,14 @@ func (bot *Bot) listenUpdates() (chan Update, error) {
 	return updates, nil
 }
 
+// Stop stops receiving updates and running scheduled jobs.
+func (bot *Bot) Stop() {
+	bot.shutdownOnce.Do(func() {
+		close(bot.shutdownChannel)
+		bot.stopMediaGroups()
+	})
+}
+
 // GetWebhookInfo allows you to fetch information about a webhook and if
 // one currently is set, along with pending update count and error messages.
 func (bot *Bot) GetWebhookInfo() (Update, error) {
@@ -490,13 +640,20 @@ func (bot *Bot) DeleteMessage(chatID int64, messageID int64) (Update, error) {
 	})
 }
 
-// KickChatMember see https://core.telegram.org/bots/api#kickchatmember
+// KickChatMember bans a user until untilDate.
+//
+// Deprecated: kickChatMember was renamed, use BanChatMember.
 func (bot *Bot) KickChatMember(chatID int64, userID int64, untilDate int64) (Update, error) {
-	return bot.MakeRequest("kickChatMember", JSONBody{
+	return bot.BanChatMember(chatID, userID, untilDate, nil)
+}
+
+// BanChatMember see https://core.telegram.org/bots/api#banchatmember
+func (bot *Bot) BanChatMember(chatID int64, userID int64, untilDate int64, extra JSONBody) (Update, error) {
+	return bot.MakeRequest("banChatMember", mergeJSON(JSONBody{
 		"chat_id":    chatID,
 		"user_id":    userID,
 		"until_date": untilDate,
-	})
+	}, extra))
 }
 
 // UnbanChatMember see https://core.telegram.org/bots/api#unbanchatmember
@@ -526,28 +683,127 @@ func (bot *Bot) ExportChatInviteLink(chatID int64) (Update, error) {
 
 // Handle lets you set the handler for some command name or
 // one of the supported endpoints.
-func (bot *Bot) Handle(endpoint string, handler interface{}) {
-	bot.handlers[endpoint] = handler
+//
+// Routes with filters are tried in registration order before the
+// unfiltered one, which acts as the fallback for the endpoint.
+func (bot *Bot) Handle(endpoint string, handler interface{}, filters ...Filter) {
+	bot.handlers[endpoint] = addRoute(bot.handlers[endpoint], &route{
+		handler: handler,
+		filters: filters,
+	})
 }
 
 // Action lets you set the handler for some command name or
 // one of the supported endpoints.
-func (bot *Bot) Action(endpoint interface{}, handler interface{}) {
+func (bot *Bot) Action(endpoint interface{}, handler interface{}, filters ...Filter) {
+	bot.actions = bot.addPattern(bot.actions, "\f", endpoint, handler, filters)
+}
+
+// Inline lets you set the handler for inline queries matching endpoint.
+// Queries no pattern matches go to the "inline_query" handler.
+func (bot *Bot) Inline(endpoint interface{}, handler interface{}, filters ...Filter) {
+	bot.inlines = bot.addPattern(bot.inlines, "\v", endpoint, handler, filters)
+}
+
+// addPattern registers a route matching text against endpoint, which is
+// either an exact string or a *regexp.Regexp, and returns the updated
+// ordered list of pattern endpoints.
+func (bot *Bot) addPattern(endpoints []string, prefix string, endpoint interface{}, handler interface{}, filters []Filter) []string {
+	var pattern string
 	switch end := endpoint.(type) {
 	case string:
-		bot.handlers["\f^"+end+"$"] = handler
+		pattern = "^" + end + "$"
 	case *regexp.Regexp:
-		bot.handlers["\f"+end.String()] = handler
+		pattern = end.String()
 	default:
 		panic("easytgbot: unsupported endpoint")
 	}
+	key := prefix + pattern
+	if _, ok := bot.handlers[key]; !ok {
+		endpoints = append(endpoints, key)
+	}
+	bot.handlers[key] = addRoute(bot.handlers[key], &route{
+		pattern: regexp.MustCompile(pattern),
+		handler: handler,
+		filters: filters,
+	})
+	return endpoints
 }
 
-// Use
+// Use adds global middleware. It runs for updates with a handler.
 func (bot *Bot) Use(middleware ...MiddlewareFunc) {
 	bot.middleware = append(bot.middleware, middleware...)
 }
 
+// UseAll adds middleware running for every update, including updates
+// without a handler, where the next handler does nothing, album parts
+// and scene steps. It runs before the middleware added with Use.
+func (bot *Bot) UseAll(middleware ...MiddlewareFunc) {
+	bot.middlewareAll = append(bot.middlewareAll, middleware...)
+}
+
+// route is a registered handler with its filters.
+type route struct {
+	pattern *regexp.Regexp
+	handler interface{}
+	filters []Filter
+}
+
+// match reports whether all filters of the route accept the update.
+func (r *route) match(update Update) bool {
+	for _, filter := range r.filters {
+		if !filter(update) {
+			return false
+		}
+	}
+	return true
+}
+
+// addRoute adds r to routes, keeping the unfiltered route last.
+// An unfiltered route replaces the previous unfiltered one.
+func addRoute(routes []*route, r *route) []*route {
+	var fallback *route
+	if n := len(routes); n > 0 && len(routes[n-1].filters) == 0 {
+		fallback = routes[n-1]
+		routes = routes[:n-1]
+	}
+	if len(r.filters) == 0 {
+		fallback = r
+	} else {
+		routes = append(routes, r)
+	}
+	if fallback != nil {
+		routes = append(routes, fallback)
+	}
+	return routes
+}
+
+// toHandlerFunc converts a registered handler to a HandlerFunc.
+func toHandlerFunc(handler interface{}) (HandlerFunc, bool) {
+	switch h := handler.(type) {
+	case HandlerFunc:
+		return h, true
+	case func(interface{}, *Bot, Update) JSONBody:
+		return h, true
+	case ErrorHandlerFunc:
+		return failOnError(h), true
+	case func(interface{}, *Bot, Update) (JSONBody, error):
+		return failOnError(h), true
+	}
+	return nil, false
+}
+
+// failOnError adapts an ErrorHandlerFunc.
+func failOnError(h ErrorHandlerFunc) HandlerFunc {
+	return func(context interface{}, bot *Bot, update Update) JSONBody {
+		res, err := h(context, bot, update)
+		if err != nil {
+			return Fail(err)
+		}
+		return res
+	}
+}
+
 // applyMiddleware
 func applyMiddleware(h HandlerFunc, middleware ...MiddlewareFunc) HandlerFunc {
 	for i := len(middleware) - 1; i >= 0; i-- {
@@ -556,62 +812,170 @@ func applyMiddleware(h HandlerFunc, middleware ...MiddlewareFunc) HandlerFunc {
 	return h
 }
 
-// ApplyHandlers is apply handler
-func (bot *Bot) ApplyHandlers(context interface{}, update Update) (JSONBody, error) {
-	updateType := update.GetType()
+// matchRoutes returns the first handler registered for endpoint
+// whose filters accept the update.
+func (bot *Bot) matchRoutes(endpoint string, update Update) (HandlerFunc, bool) {
+	for _, r := range bot.handlers[endpoint] {
+		if !r.match(update) {
+			continue
+		}
+		if handler, ok := toHandlerFunc(r.handler); ok {
+			return handler, true
+		}
+	}
+	return nil, false
+}
+
+// matchPatterns returns the first handler of the pattern endpoints
+// matching text whose filters accept the update, and its endpoint.
+func (bot *Bot) matchPatterns(endpoints []string, text string, update Update) (HandlerFunc, string, bool) {
+	for _, endpoint := range endpoints {
+		routes := bot.handlers[endpoint]
+		if len(routes) == 0 || routes[0].pattern.FindStringIndex(text) == nil {
+			continue
+		}
+		if handler, ok := bot.matchRoutes(endpoint, update); ok {
+			return handler, endpoint, true
+		}
+	}
+	return nil, "", false
+}
+
+// findHandler returns the handler for update and the name of its
+// route, see Metrics.
+func (bot *Bot) findHandler(update Update) (HandlerFunc, string, bool) {
 	// callback_query
 	callbackQuery := update.Get("callback_query")
 	if callbackQuery.Exists() {
-		data := callbackQuery.Get("data").String()
-		updateType = "\f" + data
-		// for handlers
-		for endpoint, handler := range bot.handlers {
-			// skip command
-			if endpoint[0:1] != "\f" {
-				continue
-			}
-			endpoint = endpoint[1:]
-			if regexp.MustCompile(endpoint).FindStringIndex(data) != nil {
-				if handler, ok := handler.(func(interface{}, *Bot, Update) JSONBody); ok {
-					if len(bot.middleware) > 0 {
-						handler = applyMiddleware(handler, bot.middleware...)
-					}
-					return handler(context, bot, update), nil
-				}
-			}
+		handler, endpoint, ok := bot.matchPatterns(bot.actions, callbackQuery.Get("data").String(), update)
+		return handler, "action:" + strings.TrimPrefix(endpoint, "\f"), ok
+	}
+
+	// inline_query
+	inlineQuery := update.Get("inline_query")
+	if inlineQuery.Exists() {
+		if handler, endpoint, ok := bot.matchPatterns(bot.inlines, inlineQuery.Get("query").String(), update); ok {
+			return handler, "inline:" + strings.TrimPrefix(endpoint, "\v"), true
+		}
+	}
+
+	// album
+	if update.Get("media_group").Exists() {
+		if handler, ok := bot.matchRoutes("media_group", update); ok {
+			return handler, "media_group", true
 		}
 	}
 
 	// command first
+	if command := bot.command(update); len(command) > 0 {
+		// found handler
+		if handler, ok := bot.matchRoutes(command, update); ok {
+			return handler, command, true
+		}
+	}
+
+	endpoint := update.GetType()
+	handler, ok := bot.matchRoutes(endpoint, update)
+	return handler, endpoint, ok
+}
+
+// command returns the command of update without the bot name suffix.
+func (bot *Bot) command(update Update) string {
 	command, _ := update.Command()
-	if len(command) > 0 {
-		if pos := strings.Index(command, "@"); pos > -1 {
-			botName := command[pos+1:]
-			if bot.Name != "" && strings.ToLower(botName) == strings.ToLower(bot.Name) {
-				command = command[0:pos]
-			} else if bot.Name == "" {
-				command = command[0:pos]
-			}
+	if pos := strings.Index(command, "@"); pos > -1 {
+		botName := command[pos+1:]
+		if bot.Name != "" && strings.ToLower(botName) == strings.ToLower(bot.Name) {
+			command = command[0:pos]
+		} else if bot.Name == "" {
+			command = command[0:pos]
 		}
+	}
+	return command
+}
 
-		// found handler
-		if _, ok := bot.handlers[command]; ok {
-			updateType = command
+// ApplyHandlers is apply handler
+//
+// Errors other than ErrUnhandled, including handler failures and
+// panics, returned as a PanicError, are also passed to the OnError
+// handlers.
+func (bot *Bot) ApplyHandlers(context interface{}, update Update) (JSONBody, error) {
+	start := time.Now()
+	res, route, err := bot.recoverDispatch(context, update)
+	if err == nil {
+		if err = ResultError(res); err != nil {
+			res = JSONBody{}
 		}
 	}
-	// check handler has exists
-	handler, ok := bot.handlers[updateType]
-	if !ok {
-		return JSONBody{}, fmt.Errorf("unsupported update type")
+
+	// too long for a webhook reply, send it in parts
+	if method, params := splitMethod(res); err == nil && oversized(method, params) {
+		_, err = bot.MakeRequest(method, params)
+		res = JSONBody{}
+	}
+
+	if err != nil && err != ErrUnhandled {
+		bot.reportError(err, update)
 	}
+	latency := time.Since(start)
+	bot.metrics.observeUpdate(route, err, latency)
+	bot.logger.Debug("update handled", "update_id", update.Get("update_id").Int(), "chat_id", update.ChatID(), "route", route, "latency", latency, "error", err)
+	return res, err
+}
 
-	// execute
-	if handler, ok := handler.(func(interface{}, *Bot, Update) JSONBody); ok {
-		if len(bot.middleware) > 0 {
-			handler = applyMiddleware(handler, bot.middleware...)
+// recoverDispatch is dispatch turning panics of filters, middleware,
+// scenes and handlers into a PanicError.
+func (bot *Bot) recoverDispatch(context interface{}, update Update) (res JSONBody, route string, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			res, route, err = JSONBody{}, "panic", &PanicError{Value: value, Stack: debug.Stack()}
 		}
-		return handler(context, bot, update), nil
+	}()
+	return bot.dispatch(context, update)
+}
+
+// dispatch runs the handler of update and returns the name of its
+// route. UseAll middleware runs around everything but callback
+// decoding: album parts, scenes and handlers.
+func (bot *Bot) dispatch(context interface{}, update Update) (JSONBody, string, error) {
+	update, err := bot.decodeCallback(update)
+	if err != nil {
+		return JSONBody{}, "callback", err
+	}
+	if len(bot.middlewareAll) == 0 {
+		return bot.route(context, update)
 	}
 
-	return JSONBody{}, fmt.Errorf("unsupported update type")
+	// a middleware answering itself leaves the route empty
+	route := "middleware"
+	handler := func(context interface{}, bot *Bot, update Update) JSONBody {
+		var res JSONBody
+		res, route, err = bot.route(context, update)
+		return res
+	}
+	res := applyMiddleware(handler, bot.middlewareAll...)(context, bot, update)
+	if err != nil {
+		return JSONBody{}, route, err
+	}
+	return res, route, nil
+}
+
+// route buffers album parts, or runs the scene or the handler of update
+// with the middleware added with Use.
+func (bot *Bot) route(context interface{}, update Update) (JSONBody, string, error) {
+	if bot.bufferMediaGroup(update) {
+		return JSONBody{}, "media_group_item", nil
+	}
+
+	if res, ok := bot.applyScene(context, update); ok {
+		return res, "scene", nil
+	}
+
+	handler, route, found := bot.findHandler(update)
+	if !found {
+		return JSONBody{}, "unhandled", ErrUnhandled
+	}
+	if len(bot.middleware) > 0 {
+		handler = applyMiddleware(handler, bot.middleware...)
+	}
+	return handler(context, bot, update), route, nil
 }
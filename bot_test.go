@@ -13,7 +13,12 @@ const (
 	TestToken = "951886466:AAEhTr7--GVVIkEhVuWUZZqGNC1nxMBVQ7o"
 )
 
+// getBot returns a bot on the live Bot API. Tests using it are skipped
+// unless EASYTGBOT_LIVE_TESTS is set, so the suite runs offline.
 func getBot(t *testing.T) (*easytgbot.Bot, error) {
+	if testing.Short() || os.Getenv("EASYTGBOT_LIVE_TESTS") == "" {
+		t.Skip("live Bot API test, set EASYTGBOT_LIVE_TESTS=1 to run it")
+	}
 	bot, err := easytgbot.New(
 		TestToken,
 		easytgbot.Settings{
@@ -0,0 +1,374 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryAfter returns how long to wait before retrying a request
+// refused by flood control, or zero.
+func (e Error) RetryAfter() time.Duration {
+	return time.Duration(e.Parameters.Get("retry_after").Int()) * time.Second
+}
+
+// DeliveryStatus classifies the outcome of sending to one chat.
+type DeliveryStatus string
+
+// delivery statuses
+const (
+	Delivered    DeliveryStatus = "delivered"
+	Blocked      DeliveryStatus = "blocked"
+	Deactivated  DeliveryStatus = "deactivated"
+	ChatNotFound DeliveryStatus = "chat_not_found"
+	FloodWait    DeliveryStatus = "flood_wait"
+	Failed       DeliveryStatus = "failed"
+)
+
+// ClassifyError returns the delivery status of a request error.
+func ClassifyError(err error) DeliveryStatus {
+	if err == nil {
+		return Delivered
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return Failed
+	}
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.Code == 429:
+		return FloodWait
+	case strings.Contains(message, "deactivated"):
+		return Deactivated
+	case strings.Contains(message, "chat not found"), strings.Contains(message, "user not found"):
+		return ChatNotFound
+	case apiErr.Code == 403:
+		// blocked by the user, kicked from the chat...
+		return Blocked
+	}
+	return Failed
+}
+
+// Recipients returns up to limit chat ids starting at offset; fewer
+// ids than limit ends the broadcast. The order must be stable for a
+// broadcast to resume.
+type Recipients func(offset int, limit int) ([]int64, error)
+
+// RecipientList returns recipients from a slice.
+func RecipientList(ids []int64) Recipients {
+	return func(offset int, limit int) ([]int64, error) {
+		if offset >= len(ids) {
+			return nil, nil
+		}
+		end := offset + limit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		return ids[offset:end], nil
+	}
+}
+
+// BroadcastOptions configures a broadcast.
+type BroadcastOptions struct {
+	// Recipients lists the chats to send to.
+	Recipients Recipients
+	// Message is the request sent to every chat, chat_id is set per
+	// recipient. Default method: sendMessage
+	Message JSONBody
+	// Rate is the number of messages sent per second. Default: 25
+	Rate int
+	// BatchSize is the number of recipients fetched at once. Default: 100
+	BatchSize int
+	// Checkpoint is a file the progress is saved to after every chat;
+	// a broadcast resumes from it when it exists.
+	Checkpoint string
+	// MaxFloodRetries is how often a chat is retried after flood
+	// control before it counts as failed. Default: 5
+	MaxFloodRetries int
+	// OnProgress is called after every batch and when the broadcast ends.
+	OnProgress func(BroadcastProgress)
+	// OnFailure is called for every chat the message could not reach,
+	// e.g. to forget users who blocked the bot.
+	OnFailure func(chatID int64, status DeliveryStatus, err error)
+}
+
+// BroadcastProgress is the state of a broadcast, saved as its checkpoint.
+type BroadcastProgress struct {
+	Offset   int                    `json:"offset"`
+	Counts   map[DeliveryStatus]int `json:"counts"`
+	Done     bool                   `json:"done"`
+	Canceled bool                   `json:"canceled"`
+}
+
+// Sent returns the number of chats processed.
+func (p BroadcastProgress) Sent() int {
+	total := 0
+	for _, count := range p.Counts {
+		total += count
+	}
+	return total
+}
+
+// ErrBroadcastCanceled is returned by Wait for canceled broadcasts.
+var ErrBroadcastCanceled = errors.New("broadcast canceled")
+
+// Broadcast sends a message to many chats within rate limits.
+type Broadcast struct {
+	bot  *Bot
+	opts BroadcastOptions
+
+	progress BroadcastProgress
+	paused   bool
+	canceled bool
+	err      error
+	started  bool
+	done     chan struct{}
+	cond     *sync.Cond
+	mutex    sync.Mutex
+}
+
+// Broadcast prepares a broadcast, resuming from its checkpoint if
+// any. Call Start to run it.
+//
+// Delivery is at least once: a chat is recorded in the checkpoint
+// after its message is sent, so a crash in between sends that one
+// message again on resume.
+func (bot *Bot) Broadcast(opts BroadcastOptions) (*Broadcast, error) {
+	if opts.Recipients == nil {
+		return nil, fmt.Errorf("broadcast: recipients are required")
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = 25
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxFloodRetries <= 0 {
+		opts.MaxFloodRetries = 5
+	}
+	b := &Broadcast{
+		bot:      bot,
+		opts:     opts,
+		progress: BroadcastProgress{Counts: map[DeliveryStatus]int{}},
+		done:     make(chan struct{}),
+	}
+	b.cond = sync.NewCond(&b.mutex)
+
+	if opts.Checkpoint != "" {
+		data, err := ioutil.ReadFile(opts.Checkpoint)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &b.progress); err != nil {
+				return nil, fmt.Errorf("broadcast: checkpoint: %s", err)
+			}
+			if b.progress.Counts == nil {
+				b.progress.Counts = map[DeliveryStatus]int{}
+			}
+			// a canceled broadcast resumes when started again
+			b.progress.Canceled = false
+		}
+	}
+	return b, nil
+}
+
+// Start runs the broadcast in the background.
+func (b *Broadcast) Start() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.started {
+		return
+	}
+	b.started = true
+	go b.run()
+}
+
+// Wait blocks until the broadcast ends.
+func (b *Broadcast) Wait() error {
+	<-b.done
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.err
+}
+
+// Pause stops sending after the current message.
+func (b *Broadcast) Pause() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.paused = true
+}
+
+// Resume continues a paused broadcast.
+func (b *Broadcast) Resume() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.paused = false
+	b.cond.Broadcast()
+}
+
+// Cancel stops the broadcast; its checkpoint keeps the progress.
+func (b *Broadcast) Cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.canceled = true
+	b.cond.Broadcast()
+}
+
+// Progress returns the current progress.
+func (b *Broadcast) Progress() BroadcastProgress {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.snapshot()
+}
+
+func (b *Broadcast) snapshot() BroadcastProgress {
+	progress := b.progress
+	progress.Counts = make(map[DeliveryStatus]int, len(b.progress.Counts))
+	for status, count := range b.progress.Counts {
+		progress.Counts[status] = count
+	}
+	return progress
+}
+
+// wait blocks while paused and reports whether to go on.
+func (b *Broadcast) wait() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for b.paused && !b.canceled {
+		b.cond.Wait()
+	}
+	return !b.canceled
+}
+
+// sleep waits for d unless the broadcast is canceled meanwhile.
+func (b *Broadcast) sleep(d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if !b.wait() {
+			return false
+		}
+		step := time.Until(deadline)
+		if step > 100*time.Millisecond {
+			step = 100 * time.Millisecond
+		}
+		time.Sleep(step)
+	}
+	return b.wait()
+}
+
+func (b *Broadcast) run() {
+	err := b.send()
+
+	b.mutex.Lock()
+	if err == nil && b.canceled {
+		err = ErrBroadcastCanceled
+		b.progress.Canceled = true
+	} else if err == nil {
+		b.progress.Done = true
+	}
+	b.err = err
+	b.mutex.Unlock()
+
+	if saveErr := b.save(); saveErr != nil && err == nil {
+		b.mutex.Lock()
+		b.err = saveErr
+		b.mutex.Unlock()
+	}
+	if b.opts.OnProgress != nil {
+		b.opts.OnProgress(b.Progress())
+	}
+	close(b.done)
+}
+
+func (b *Broadcast) send() error {
+	method, params := splitMethod(b.opts.Message)
+	if method == "" {
+		method = "sendMessage"
+	}
+	interval := time.Second / time.Duration(b.opts.Rate)
+	next := time.Now()
+
+	for {
+		b.mutex.Lock()
+		offset, done := b.progress.Offset, b.progress.Done
+		b.mutex.Unlock()
+		if done || !b.wait() {
+			return nil
+		}
+
+		ids, err := b.opts.Recipients(offset, b.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, chatID := range ids {
+			status, err := b.deliver(method, params, chatID, interval, &next)
+			if status == "" {
+				return nil
+			}
+			b.mutex.Lock()
+			b.progress.Counts[status]++
+			b.progress.Offset++
+			b.mutex.Unlock()
+			if err := b.save(); err != nil {
+				return err
+			}
+			if status != Delivered && b.opts.OnFailure != nil {
+				b.opts.OnFailure(chatID, status, err)
+			}
+		}
+
+		if len(ids) < b.opts.BatchSize {
+			return nil
+		}
+		if b.opts.OnProgress != nil {
+			b.opts.OnProgress(b.Progress())
+		}
+	}
+}
+
+// deliver sends to one chat, waiting out flood control; it returns an
+// empty status when canceled.
+func (b *Broadcast) deliver(method string, params JSONBody, chatID int64, interval time.Duration, next *time.Time) (DeliveryStatus, error) {
+	request := mergeJSON(JSONBody{}, params)
+	request["chat_id"] = chatID
+
+	for retry := 0; ; retry++ {
+		if !b.sleep(time.Until(*next)) {
+			return "", nil
+		}
+		*next = time.Now().Add(interval)
+
+		_, err := b.bot.MakeRequest(method, request)
+		status := ClassifyError(err)
+		if status != FloodWait {
+			return status, err
+		}
+		if retry >= b.opts.MaxFloodRetries {
+			return Failed, err
+		}
+		wait := time.Second
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter() > 0 {
+			wait = apiErr.RetryAfter()
+		}
+		*next = time.Now().Add(wait)
+	}
+}
+
+// save writes the checkpoint.
+func (b *Broadcast) save() error {
+	if b.opts.Checkpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(b.Progress())
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.opts.Checkpoint, data)
+}
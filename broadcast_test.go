@@ -0,0 +1,102 @@
+package easytgbot
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBroadcast(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	checkpoint := filepath.Join(t.TempDir(), "broadcast.json")
+	flooded := false
+	saved := ""
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		switch params["chat_id"].(float64) {
+		case 2:
+			// the first chat is saved before the batch ends
+			if data, err := ioutil.ReadFile(checkpoint); err == nil {
+				saved = NewUpdate(string(data)).Get("offset").String()
+			}
+			return nil, &Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+		case 3:
+			if !flooded {
+				flooded = true
+				return nil, &Error{Code: 429, Message: "Too Many Requests: retry after 1", Parameters: NewUpdate(`{"retry_after":1}`)}
+			}
+		case 4:
+			return nil, &Error{Code: 400, Message: "Bad Request: chat not found"}
+		}
+		return JSONBody{"message_id": 1}, nil
+	}
+
+	failures := map[int64]DeliveryStatus{}
+	opts := BroadcastOptions{
+		Recipients: RecipientList([]int64{1, 2, 3, 4, 5}),
+		Message:    JSONBody{"text": "news"},
+		Rate:       100,
+		BatchSize:  2,
+		Checkpoint: checkpoint,
+		OnFailure: func(chatID int64, status DeliveryStatus, err error) {
+			failures[chatID] = status
+		},
+	}
+	b, err := bot.Broadcast(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Start()
+	if err := b.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := b.Progress()
+	if !progress.Done || progress.Offset != 5 || progress.Sent() != 5 || progress.Counts[Delivered] != 3 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	if saved != "1" {
+		t.Errorf("checkpoint offset before the second chat = %q, want 1", saved)
+	}
+	if failures[2] != Blocked || failures[4] != ChatNotFound || len(failures) != 2 {
+		t.Errorf("unexpected failures %v", failures)
+	}
+	if calls := api.Calls(); len(calls) != 6 || calls[0].Method != "sendMessage" || calls[0].Params["text"] != "news" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	// a finished broadcast does not send again
+	b, _ = bot.Broadcast(opts)
+	b.Start()
+	b.Wait()
+	if calls := api.Calls(); len(calls) != 6 {
+		t.Errorf("finished broadcast sent again: %d calls", len(calls))
+	}
+
+	// resume from a checkpoint
+	ioutil.WriteFile(checkpoint, []byte(`{"offset":4,"counts":{"delivered":4}}`), 0644)
+	b, _ = bot.Broadcast(opts)
+	b.Start()
+	b.Wait()
+	calls := api.Calls()
+	if len(calls) != 7 || calls[6].Params["chat_id"] != float64(5) {
+		t.Errorf("resume sent %v", calls[6:])
+	}
+}
+
+func TestBroadcastCancel(t *testing.T) {
+	bot, _ := newTestBot(t, Settings{})
+	ids := make([]int64, 1000)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	b, _ := bot.Broadcast(BroadcastOptions{Recipients: RecipientList(ids), Message: JSONBody{"text": "news"}, Rate: 50})
+	b.Start()
+	b.Pause()
+	b.Cancel()
+	if err := b.Wait(); err != ErrBroadcastCanceled {
+		t.Errorf("got %v", err)
+	}
+	if p := b.Progress(); !p.Canceled || p.Done || p.Offset >= len(ids) {
+		t.Errorf("unexpected progress %+v", p)
+	}
+}
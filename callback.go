@@ -0,0 +1,232 @@
+package easytgbot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CallbackDataLimit is the maximum size of callback_data in bytes.
+const CallbackDataLimit = 64
+
+// callback data starting with callbackStored references a payload kept in
+// the store, and callbackSignature separates the payload from its HMAC.
+const (
+	callbackStored    = "~"
+	callbackSignature = "."
+	callbackSigLength = 11
+)
+
+// CallbackCodec signs callback_data and moves payloads larger than
+// CallbackDataLimit to a store, leaving a short reference in the button.
+//
+// With a secret set every callback_data must be produced by Encode;
+// unsigned or tampered data is rejected. Data starting with "~" is
+// reserved for stored payloads.
+type CallbackCodec struct {
+	Secret []byte
+	Store  SessionStore
+}
+
+// NewCallbackCodec creates a codec. Without a store, large payloads
+// are kept in memory for a week.
+func NewCallbackCodec(secret string, store SessionStore) *CallbackCodec {
+	if store == nil {
+		store = NewMemoryStore(7 * 24 * time.Hour)
+	}
+	return &CallbackCodec{
+		Secret: []byte(secret),
+		Store:  store,
+	}
+}
+
+// Encode returns the callback_data for data.
+func (c *CallbackCodec) Encode(data string) (string, error) {
+	if len(c.Secret) > 0 {
+		data += callbackSignature + c.sign(data)
+	}
+	if len(data) <= CallbackDataLimit && !strings.HasPrefix(data, callbackStored) {
+		return data, nil
+	}
+	key := make([]byte, 9)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	ref := base64.RawURLEncoding.EncodeToString(key)
+	if err := c.Store.Set("callback:"+ref, JSONBody{"data": data}); err != nil {
+		return "", err
+	}
+	return callbackStored + ref, nil
+}
+
+// Decode returns the data encoded in callback_data.
+func (c *CallbackCodec) Decode(data string) (string, error) {
+	if strings.HasPrefix(data, callbackStored) {
+		stored, err := c.Store.Get("callback:" + data[len(callbackStored):])
+		if err != nil {
+			return "", err
+		}
+		payload, ok := stored["data"].(string)
+		if !ok {
+			return "", fmt.Errorf("callback data has expired")
+		}
+		data = payload
+	}
+	if len(c.Secret) == 0 {
+		return data, nil
+	}
+	pos := strings.LastIndex(data, callbackSignature)
+	if pos < 0 || !hmac.Equal([]byte(data[pos+1:]), []byte(c.sign(data[:pos]))) {
+		return "", fmt.Errorf("callback data has an invalid signature")
+	}
+	return data[:pos], nil
+}
+
+func (c *CallbackCodec) sign(data string) string {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:callbackSigLength]
+}
+
+// active reports whether callback data needs decoding.
+func (c *CallbackCodec) active(data string) bool {
+	return len(c.Secret) > 0 || strings.HasPrefix(data, callbackStored)
+}
+
+// EncodeCallback returns the callback_data for data using the bot codec.
+func (bot *Bot) EncodeCallback(data string) (string, error) {
+	return bot.callbackCodec.Encode(data)
+}
+
+// PackCallback packs values with d and encodes them with the bot codec.
+func (bot *Bot) PackCallback(d *CallbackData, values ...interface{}) (string, error) {
+	data, err := d.Pack(values...)
+	if err != nil {
+		return "", err
+	}
+	return bot.EncodeCallback(data)
+}
+
+// decodeCallback replaces encoded callback data of update with the
+// decoded data, so that Action patterns and handlers see the payload.
+func (bot *Bot) decodeCallback(update Update) (Update, error) {
+	data := update.Get("callback_query.data")
+	if !data.Exists() || !bot.callbackCodec.active(data.String()) {
+		return update, nil
+	}
+	decoded, err := bot.callbackCodec.Decode(data.String())
+	if err != nil {
+		return update, err
+	}
+	return update.set([]string{"callback_query", "data"}, decoded)
+}
+
+// set returns a copy of update with the value at path replaced.
+func (update Update) set(path []string, value interface{}) (Update, error) {
+	decoder := json.NewDecoder(strings.NewReader(update.Raw))
+	decoder.UseNumber()
+	root := map[string]interface{}{}
+	if err := decoder.Decode(&root); err != nil {
+		return update, err
+	}
+	node := root
+	for _, key := range path[:len(path)-1] {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			node[key] = child
+		}
+		node = child
+	}
+	node[path[len(path)-1]] = value
+	raw, err := json.Marshal(root)
+	if err != nil {
+		return update, err
+	}
+	return NewUpdate(string(raw)), nil
+}
+
+// CallbackData packs typed fields behind a prefix, e.g.
+// NewCallbackData("vote", "action", "id") packs "vote:up:42".
+type CallbackData struct {
+	Prefix string
+	Fields []string
+}
+
+// CallbackValues are the fields unpacked from callback data.
+type CallbackValues map[string]string
+
+// NewCallbackData creates a callback data layout.
+func NewCallbackData(prefix string, fields ...string) *CallbackData {
+	if strings.Contains(prefix, ":") {
+		panic("easytgbot: callback data prefix contains ':'")
+	}
+	return &CallbackData{
+		Prefix: prefix,
+		Fields: fields,
+	}
+}
+
+// Pack packs one value per field.
+func (d *CallbackData) Pack(values ...interface{}) (string, error) {
+	if len(values) != len(d.Fields) {
+		return "", fmt.Errorf("callback data %s needs %d values, got %d", d.Prefix, len(d.Fields), len(values))
+	}
+	parts := []string{d.Prefix}
+	for _, value := range values {
+		parts = append(parts, callbackEscaper.Replace(fmt.Sprintf("%v", value)))
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// Pattern matches callback data packed by d, for use with Bot.Action.
+func (d *CallbackData) Pattern() *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(d.Prefix) + "(:|$)")
+}
+
+// Unpack unpacks data packed by d.
+func (d *CallbackData) Unpack(data string) (CallbackValues, error) {
+	parts := strings.Split(data, ":")
+	if parts[0] != d.Prefix || len(parts) != len(d.Fields)+1 {
+		return nil, fmt.Errorf("callback data %q does not match %s", data, d.Prefix)
+	}
+	values := CallbackValues{}
+	for i, field := range d.Fields {
+		values[field] = callbackUnescaper.Replace(parts[i+1])
+	}
+	return values, nil
+}
+
+// Parse unpacks the callback data of a callback query update.
+func (d *CallbackData) Parse(update Update) (CallbackValues, error) {
+	return d.Unpack(update.Get("callback_query.data").String())
+}
+
+var (
+	callbackEscaper   = strings.NewReplacer("%", "%25", ":", "%3A")
+	callbackUnescaper = strings.NewReplacer("%3A", ":", "%25", "%")
+)
+
+// String returns a field value.
+func (v CallbackValues) String(field string) string {
+	return v[field]
+}
+
+// Int returns a field value as an integer.
+func (v CallbackValues) Int(field string) int64 {
+	i, _ := strconv.ParseInt(v[field], 10, 64)
+	return i
+}
+
+// Bool returns a field value as a boolean.
+func (v CallbackValues) Bool(field string) bool {
+	b, _ := strconv.ParseBool(v[field])
+	return b
+}
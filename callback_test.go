@@ -0,0 +1,58 @@
+package easytgbot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallbackData(t *testing.T) {
+	vote := NewCallbackData("vote", "action", "id")
+	data, err := vote.Pack("up:down", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vote.Pattern().MatchString(data) {
+		t.Errorf("pattern does not match %s", data)
+	}
+	values, err := vote.Unpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.String("action") != "up:down" || values.Int("id") != 42 {
+		t.Errorf("unexpected values %v", values)
+	}
+	if _, err := vote.Pack("up"); err == nil {
+		t.Error("expected an error for missing values")
+	}
+}
+
+func TestCallbackCodec(t *testing.T) {
+	bot, _ := New("token", Settings{CallbackSecret: "secret"})
+	vote := NewCallbackData("vote", "action", "id")
+	bot.Action(vote.Pattern(), func(context interface{}, bot *Bot, update Update) JSONBody {
+		values, _ := vote.Parse(update)
+		return JSONBody{"action": values.String("action")}
+	})
+
+	long := strings.Repeat("x", 100)
+	for _, action := range []string{"up", long} {
+		data, err := bot.PackCallback(vote, action, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > CallbackDataLimit {
+			t.Errorf("callback data is too long: %s", data)
+		}
+		res, err := bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"1","from":{"id":7},"data":"`+data+`"}}`))
+		if err != nil || res["action"] != action {
+			t.Errorf("got %v (%v), want %s", res["action"], err, action)
+		}
+	}
+
+	// forged data is rejected
+	for _, data := range []string{"vote:up:1", "vote:up:1.AAAAAAAAAAA", "~missing"} {
+		if _, err := bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"1","from":{"id":7},"data":"`+data+`"}}`)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
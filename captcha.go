@@ -0,0 +1,318 @@
+package easytgbot
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Challenge generates captcha questions for new members.
+type Challenge interface {
+	// New returns the question, the button labels and the index of the
+	// correct button.
+	New() (question string, options []string, answer int)
+}
+
+// ChallengeFunc adapts a function to a Challenge.
+type ChallengeFunc func() (string, []string, int)
+
+// New implements Challenge.
+func (f ChallengeFunc) New() (string, []string, int) {
+	return f()
+}
+
+// ButtonChallenge asks the user to press a single button.
+type ButtonChallenge struct {
+	Label string // Default: "I'm not a robot"
+}
+
+// New implements Challenge.
+func (c ButtonChallenge) New() (string, []string, int) {
+	label := c.Label
+	if label == "" {
+		label = "I'm not a robot"
+	}
+	return "press the button", []string{label}, 0
+}
+
+// MathChallenge asks the user to add two numbers.
+type MathChallenge struct {
+	Options int // Default: 4
+}
+
+// New implements Challenge.
+func (c MathChallenge) New() (string, []string, int) {
+	count := c.Options
+	if count < 2 {
+		count = 4
+	}
+	// wrong answers come from 1..limit, enough for every option
+	limit := 20
+	if 2*count > limit {
+		limit = 2 * count
+	}
+	a, b := randomInt(10)+1, randomInt(10)+1
+	answer := randomInt(count)
+	options := make([]string, count)
+	used := map[int]bool{a + b: true}
+	for i := range options {
+		if i == answer {
+			options[i] = strconv.Itoa(a + b)
+			continue
+		}
+		wrong := a + b
+		for used[wrong] {
+			wrong = randomInt(limit) + 1
+		}
+		used[wrong] = true
+		options[i] = strconv.Itoa(wrong)
+	}
+	return fmt.Sprintf("what is %d + %d?", a, b), options, answer
+}
+
+// EmojiChallenge asks the user to pick an emoji by name.
+type EmojiChallenge struct {
+	// Emojis maps emojis to their names. Default: a set of animals
+	Emojis  map[string]string
+	Options int // Default: 4
+}
+
+var defaultEmojis = map[string]string{
+	"🐶": "dog", "🐱": "cat", "🐭": "mouse", "🐰": "rabbit", "🦊": "fox",
+	"🐻": "bear", "🐼": "panda", "🐨": "koala", "🐯": "tiger", "🦁": "lion",
+}
+
+// New implements Challenge.
+func (c EmojiChallenge) New() (string, []string, int) {
+	emojis := c.Emojis
+	if len(emojis) == 0 {
+		emojis = defaultEmojis
+	}
+	all := make([]string, 0, len(emojis))
+	for emoji := range emojis {
+		all = append(all, emoji)
+	}
+	count := c.Options
+	if count < 2 {
+		count = 4
+	}
+	if count > len(all) {
+		count = len(all)
+	}
+	// partial shuffle
+	for i := 0; i < count; i++ {
+		j := i + randomInt(len(all)-i)
+		all[i], all[j] = all[j], all[i]
+	}
+	options := all[:count]
+	answer := randomInt(count)
+	return fmt.Sprintf("press the %s", emojis[options[answer]]), options, answer
+}
+
+// randomInt returns a random number in [0, n).
+func randomInt(n int) int {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(i.Int64())
+}
+
+// CaptchaOptions configures new member verification.
+type CaptchaOptions struct {
+	// Challenge generates the questions. Default: ButtonChallenge
+	Challenge Challenge
+
+	// Timeout kicks users who don't answer in time. Default: 2m
+	Timeout time.Duration
+
+	// Text of the challenge message, with {name}, {question} and
+	// {seconds} placeholders.
+	// Default: "{name}, {question} You have {seconds} seconds."
+	Text string
+
+	// OnPass and OnFail are called after verification succeeds or fails.
+	OnPass func(bot *Bot, chatID int64, userID int64)
+	OnFail func(bot *Bot, chatID int64, userID int64)
+}
+
+// Captcha restricts new members until they answer a challenge.
+type Captcha struct {
+	opts CaptchaOptions
+	data *CallbackData
+
+	mutex   sync.Mutex
+	pending map[string]*captchaPending
+}
+
+type captchaPending struct {
+	answer    int
+	messageID int64
+	joinID    int64
+	timer     *time.Timer
+}
+
+// NewCaptcha creates a captcha; add it with bot.UseAll(captcha.Middleware()).
+// The bot must be an administrator allowed to restrict, ban and delete.
+func NewCaptcha(opts CaptchaOptions) *Captcha {
+	if opts.Challenge == nil {
+		opts.Challenge = ButtonChallenge{}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+	if opts.Text == "" {
+		opts.Text = "{name}, {question} You have {seconds} seconds."
+	}
+	return &Captcha{
+		opts:    opts,
+		data:    NewCallbackData("captcha", "user", "choice"),
+		pending: make(map[string]*captchaPending),
+	}
+}
+
+// Middleware challenges members joining a group and handles their
+// answers. Join messages still reach the handlers.
+func (c *Captcha) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			if values, err := c.data.Parse(update); err == nil {
+				return c.answer(bot, update, values)
+			}
+			message := update.Get("message")
+			chatID := message.Get("chat.id").Int()
+			for _, member := range message.Get("new_chat_members").Array() {
+				if !member.Get("is_bot").Bool() {
+					c.challenge(bot, chatID, member, message.Get("message_id").Int())
+				}
+			}
+			if left := message.Get("left_chat_member.id").Int(); left != 0 {
+				c.finish(chatID, left)
+			}
+			return next(context, bot, update)
+		}
+	}
+}
+
+// challenge restricts a new member and sends the question.
+func (c *Captcha) challenge(bot *Bot, chatID int64, member Update, joinID int64) {
+	userID := member.Get("id").Int()
+	if _, err := bot.Mute(chatID, userID, ""); err != nil {
+		bot.logger.Warn("captcha: restrict", "chat_id", chatID, "user_id", userID, "error", err)
+		return
+	}
+
+	question, options, answer := c.opts.Challenge.New()
+	buttons := []JSONBody{}
+	for i, option := range options {
+		data, err := bot.PackCallback(c.data, userID, i)
+		if err != nil {
+			bot.logger.Error("captcha: pack callback", "chat_id", chatID, "error", err)
+			return
+		}
+		buttons = append(buttons, JSONBody{"text": option, "callback_data": data})
+	}
+	name := member.Get("first_name").String()
+	text := strings.NewReplacer(
+		"{name}", fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, userID, escapeHTML(name)),
+		"{question}", question,
+		"{seconds}", strconv.Itoa(int(c.opts.Timeout/time.Second)),
+	).Replace(c.opts.Text)
+	res, err := bot.SendMessage(chatID, text, JSONBody{
+		"parse_mode":   "HTML",
+		"reply_markup": JSONBody{"inline_keyboard": [][]JSONBody{buttons}},
+	})
+	if err != nil {
+		bot.logger.Warn("captcha: send challenge", "chat_id", chatID, "user_id", userID, "error", err)
+	}
+
+	pending := &captchaPending{
+		answer:    answer,
+		messageID: res.Get("message_id").Int(),
+		joinID:    joinID,
+	}
+	c.mutex.Lock()
+	if old, ok := c.pending[captchaKey(chatID, userID)]; ok {
+		old.timer.Stop()
+	}
+	c.pending[captchaKey(chatID, userID)] = pending
+	pending.timer = time.AfterFunc(c.opts.Timeout, func() {
+		if c.finish(chatID, userID) == pending {
+			c.fail(bot, chatID, userID, pending)
+		}
+	})
+	c.mutex.Unlock()
+}
+
+// answer checks the button pressed by a new member.
+func (c *Captcha) answer(bot *Bot, update Update, values CallbackValues) JSONBody {
+	chatID := update.ChatID()
+	userID := values.Int("user")
+	if update.FromID() != userID {
+		return update.AnswerCallbackQuery("This question is not for you.", nil)
+	}
+	pending := c.finish(chatID, userID)
+	if pending == nil {
+		return update.AnswerCallbackQuery("", JSONBody{"show_alert": false})
+	}
+	if int(values.Int("choice")) != pending.answer {
+		c.fail(bot, chatID, userID, pending)
+		return update.AnswerCallbackQuery("Wrong answer.", nil)
+	}
+
+	if _, err := bot.Unmute(chatID, userID); err != nil {
+		bot.logger.Warn("captcha: unmute", "chat_id", chatID, "user_id", userID, "error", err)
+	}
+	if pending.messageID != 0 {
+		bot.DeleteMessage(chatID, pending.messageID)
+	}
+	if c.opts.OnPass != nil {
+		c.opts.OnPass(bot, chatID, userID)
+	}
+	return update.AnswerCallbackQuery("Welcome!", JSONBody{"show_alert": false})
+}
+
+// fail kicks the user and removes the captcha messages.
+func (c *Captcha) fail(bot *Bot, chatID int64, userID int64, pending *captchaPending) {
+	if _, err := bot.Kick(chatID, userID); err != nil {
+		bot.logger.Warn("captcha: kick", "chat_id", chatID, "user_id", userID, "error", err)
+	}
+	if pending.messageID != 0 {
+		bot.DeleteMessage(chatID, pending.messageID)
+	}
+	if pending.joinID != 0 {
+		bot.DeleteMessage(chatID, pending.joinID)
+	}
+	if c.opts.OnFail != nil {
+		c.opts.OnFail(bot, chatID, userID)
+	}
+}
+
+// finish removes and returns the pending challenge of a user.
+func (c *Captcha) finish(chatID int64, userID int64) *captchaPending {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := captchaKey(chatID, userID)
+	pending, ok := c.pending[key]
+	if !ok {
+		return nil
+	}
+	pending.timer.Stop()
+	delete(c.pending, key)
+	return pending
+}
+
+func captchaKey(chatID int64, userID int64) string {
+	return fmt.Sprintf("%d:%d", chatID, userID)
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeHTML escapes text for the HTML parse mode.
+func escapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
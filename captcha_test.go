@@ -0,0 +1,80 @@
+package easytgbot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func captchaBot(t *testing.T, timeout time.Duration) (*Bot, *fakeAPI, *Captcha) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		if method == "sendMessage" {
+			return JSONBody{"message_id": 50, "reply_markup": params["reply_markup"]}, nil
+		}
+		return true, nil
+	}
+	captcha := NewCaptcha(CaptchaOptions{Challenge: MathChallenge{}, Timeout: timeout})
+	bot.UseAll(captcha.Middleware())
+	bot.Handle("new_chat_members", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"welcome": true}
+	})
+	res, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":10,"from":{"id":7},"chat":{"id":-100,"type":"supergroup"},"new_chat_members":[{"id":7,"is_bot":false,"first_name":"Lukin"}]}}`))
+	if err != nil || res["welcome"] != true {
+		t.Fatalf("join message did not reach the handler: %v (%v)", res, err)
+	}
+	return bot, api, captcha
+}
+
+func TestCaptchaPass(t *testing.T) {
+	bot, api, captcha := captchaBot(t, time.Minute)
+	calls := api.Calls()
+	if calls[0].Method != "restrictChatMember" || calls[1].Method != "sendMessage" {
+		t.Fatalf("unexpected calls %+v", calls)
+	}
+	pending := captcha.pending["-100:7"]
+	buttons := calls[1].Params["reply_markup"].(map[string]interface{})["inline_keyboard"].([]interface{})[0].([]interface{})
+	data := buttons[pending.answer].(map[string]interface{})["callback_data"].(string)
+
+	// other users can't answer
+	res, _ := bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"1","from":{"id":8},"message":{"message_id":50,"chat":{"id":-100}},"data":"`+data+`"}}`))
+	if res["text"] != "This question is not for you." {
+		t.Errorf("unexpected answer %v", res)
+	}
+	res, _ = bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"2","from":{"id":7},"message":{"message_id":50,"chat":{"id":-100}},"data":"`+data+`"}}`))
+	if res["text"] != "Welcome!" {
+		t.Errorf("unexpected answer %v", res)
+	}
+	calls = api.Calls()
+	permissions := calls[2].Params["permissions"].(map[string]interface{})
+	if calls[2].Method != "restrictChatMember" || permissions["can_send_messages"] != true || calls[3].Method != "deleteMessage" {
+		t.Errorf("unexpected calls %+v", calls[2:])
+	}
+}
+
+func TestCaptchaTimeout(t *testing.T) {
+	_, api, _ := captchaBot(t, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	methods := ""
+	for _, call := range api.Calls()[2:] {
+		methods += call.Method
+		if id, ok := call.Params["message_id"].(float64); ok {
+			methods += " " + strconv.Itoa(int(id))
+		}
+		methods += ";"
+	}
+	if methods != "banChatMember;unbanChatMember;deleteMessage 50;deleteMessage 10;" {
+		t.Errorf("unexpected calls %s", methods)
+	}
+}
+
+func TestCaptchaMathOptions(t *testing.T) {
+	question, options, answer := MathChallenge{Options: 30}.New()
+	seen := map[string]bool{}
+	for _, option := range options {
+		seen[option] = true
+	}
+	if len(options) != 30 || len(seen) != 30 || answer < 0 || answer >= 30 {
+		t.Errorf("unexpected challenge %q %v %d", question, options, answer)
+	}
+}
@@ -0,0 +1,156 @@
+package easytgbot
+
+// ChatMemberUpdated is a typed view of my_chat_member and chat_member
+// updates, see https://core.telegram.org/bots/api#chatmemberupdated
+type ChatMemberUpdated struct {
+	Chat Update
+	// From is the user who made the change
+	From Update
+	// Member is the user whose status changed
+	Member Update
+	// Old and New are the chat member before and after the change
+	Old       Update
+	New       Update
+	OldStatus string
+	NewStatus string
+	// Mine is true when the member is the bot itself (my_chat_member)
+	Mine bool
+}
+
+// ChatMemberUpdated returns the view of a my_chat_member or
+// chat_member update.
+func (update Update) ChatMemberUpdated() (ChatMemberUpdated, bool) {
+	for _, t := range []string{"my_chat_member", "chat_member"} {
+		node := update.Get(t)
+		if !node.Exists() {
+			continue
+		}
+		return ChatMemberUpdated{
+			Chat:      node.Get("chat"),
+			From:      node.Get("from"),
+			Member:    node.Get("new_chat_member.user"),
+			Old:       node.Get("old_chat_member"),
+			New:       node.Get("new_chat_member"),
+			OldStatus: node.Get("old_chat_member.status").String(),
+			NewStatus: node.Get("new_chat_member.status").String(),
+			Mine:      t == "my_chat_member",
+		}, true
+	}
+	return ChatMemberUpdated{}, false
+}
+
+// isMember reports whether a chat member object is in the chat.
+func isMember(member Update) bool {
+	switch member.Get("status").String() {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return member.Get("is_member").Bool()
+	}
+	return false
+}
+
+func isAdmin(status string) bool {
+	return status == "creator" || status == "administrator"
+}
+
+// Joined reports whether the member entered the chat.
+func (c ChatMemberUpdated) Joined() bool {
+	return !isMember(c.Old) && isMember(c.New)
+}
+
+// Left reports whether the member left the chat on their own.
+func (c ChatMemberUpdated) Left() bool {
+	return isMember(c.Old) && !isMember(c.New) && c.NewStatus != "kicked"
+}
+
+// Banned reports whether the member was banned.
+func (c ChatMemberUpdated) Banned() bool {
+	return c.NewStatus == "kicked" && c.OldStatus != "kicked"
+}
+
+// Unbanned reports whether the member was unbanned.
+func (c ChatMemberUpdated) Unbanned() bool {
+	return c.OldStatus == "kicked" && c.NewStatus != "kicked"
+}
+
+// Promoted reports whether the member became an administrator.
+func (c ChatMemberUpdated) Promoted() bool {
+	return !isAdmin(c.OldStatus) && isAdmin(c.NewStatus)
+}
+
+// Demoted reports whether the member lost administrator rights.
+func (c ChatMemberUpdated) Demoted() bool {
+	return isAdmin(c.OldStatus) && !isAdmin(c.NewStatus)
+}
+
+// Restricted reports whether the member was restricted.
+func (c ChatMemberUpdated) Restricted() bool {
+	return c.NewStatus == "restricted" && c.OldStatus != "restricted"
+}
+
+// BotAddedToGroup reports whether the bot was added to a group,
+// supergroup or channel.
+func (c ChatMemberUpdated) BotAddedToGroup() bool {
+	return c.Mine && c.Chat.Get("type").String() != "private" && c.Joined()
+}
+
+// BotRemovedFromGroup reports whether the bot was removed from a group,
+// supergroup or channel.
+func (c ChatMemberUpdated) BotRemovedFromGroup() bool {
+	return c.Mine && c.Chat.Get("type").String() != "private" && isMember(c.Old) && !isMember(c.New)
+}
+
+// BotBlocked reports whether a user blocked the bot in a private chat.
+func (c ChatMemberUpdated) BotBlocked() bool {
+	return c.Mine && c.Chat.Get("type").String() == "private" && c.Banned()
+}
+
+// memberFilter accepts chat member updates matching test.
+func memberFilter(test func(ChatMemberUpdated) bool) Filter {
+	return func(update Update) bool {
+		c, ok := update.ChatMemberUpdated()
+		return ok && test(c)
+	}
+}
+
+// OnBotAdded handles the bot being added to a group or channel.
+func (bot *Bot) OnBotAdded(handler interface{}) {
+	bot.Handle("my_chat_member", handler, memberFilter(ChatMemberUpdated.BotAddedToGroup))
+}
+
+// OnBotRemoved handles the bot being removed from a group or channel.
+func (bot *Bot) OnBotRemoved(handler interface{}) {
+	bot.Handle("my_chat_member", handler, memberFilter(ChatMemberUpdated.BotRemovedFromGroup))
+}
+
+// OnBotBlocked handles a user blocking the bot.
+func (bot *Bot) OnBotBlocked(handler interface{}) {
+	bot.Handle("my_chat_member", handler, memberFilter(ChatMemberUpdated.BotBlocked))
+}
+
+// OnUserJoined handles users joining a chat. The bot must be an
+// administrator and request "chat_member" in allowed_updates.
+func (bot *Bot) OnUserJoined(handler interface{}) {
+	bot.Handle("chat_member", handler, memberFilter(ChatMemberUpdated.Joined))
+}
+
+// OnUserLeft handles users leaving a chat, see OnUserJoined.
+func (bot *Bot) OnUserLeft(handler interface{}) {
+	bot.Handle("chat_member", handler, memberFilter(ChatMemberUpdated.Left))
+}
+
+// OnUserBanned handles users being banned, see OnUserJoined.
+func (bot *Bot) OnUserBanned(handler interface{}) {
+	bot.Handle("chat_member", handler, memberFilter(ChatMemberUpdated.Banned))
+}
+
+// OnUserPromoted handles users becoming administrators, see OnUserJoined.
+func (bot *Bot) OnUserPromoted(handler interface{}) {
+	bot.Handle("chat_member", handler, memberFilter(ChatMemberUpdated.Promoted))
+}
+
+// OnUserDemoted handles administrators losing their rights, see OnUserJoined.
+func (bot *Bot) OnUserDemoted(handler interface{}) {
+	bot.Handle("chat_member", handler, memberFilter(ChatMemberUpdated.Demoted))
+}
@@ -0,0 +1,76 @@
+package easytgbot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func memberUpdate(node string, chatType string, oldStatus string, newStatus string) Update {
+	return NewUpdate(fmt.Sprintf(`{"update_id":1,"%s":{"chat":{"id":-100,"type":%q},"from":{"id":1},"date":0,`+
+		`"old_chat_member":{"user":{"id":2},"status":%q},"new_chat_member":{"user":{"id":2},"status":%q}}}`,
+		node, chatType, oldStatus, newStatus))
+}
+
+func TestChatMemberUpdated(t *testing.T) {
+	update := memberUpdate("chat_member", "supergroup", "left", "member")
+	if _, err := update.Message(); err == nil {
+		t.Error("chat member update is not a message")
+	}
+	if update.ChatID() != -100 || update.FromID() != 1 {
+		t.Errorf("unexpected chat %d or from %d", update.ChatID(), update.FromID())
+	}
+	if update.GetType() != "chat_member" {
+		t.Errorf("unexpected type %s", update.GetType())
+	}
+
+	cases := []struct {
+		old, new string
+		test     func(ChatMemberUpdated) bool
+	}{
+		{"left", "member", ChatMemberUpdated.Joined},
+		{"member", "left", ChatMemberUpdated.Left},
+		{"member", "kicked", ChatMemberUpdated.Banned},
+		{"member", "administrator", ChatMemberUpdated.Promoted},
+		{"administrator", "member", ChatMemberUpdated.Demoted},
+		{"member", "restricted", ChatMemberUpdated.Restricted},
+	}
+	for _, c := range cases {
+		view, _ := memberUpdate("chat_member", "supergroup", c.old, c.new).ChatMemberUpdated()
+		if !c.test(view) {
+			t.Errorf("%s -> %s not detected", c.old, c.new)
+		}
+	}
+	view, _ := memberUpdate("chat_member", "supergroup", "member", "kicked").ChatMemberUpdated()
+	if view.Left() {
+		t.Error("a ban is not a leave")
+	}
+}
+
+func TestChatMemberRoutes(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	route := func(name string) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			return JSONBody{"route": name}
+		}
+	}
+	bot.OnBotAdded(route("added"))
+	bot.OnBotBlocked(route("blocked"))
+	bot.OnUserJoined(route("joined"))
+	bot.OnUserLeft(route("left"))
+
+	cases := map[string]Update{
+		"added":   memberUpdate("my_chat_member", "group", "left", "member"),
+		"blocked": memberUpdate("my_chat_member", "private", "member", "kicked"),
+		"joined":  memberUpdate("chat_member", "supergroup", "left", "member"),
+		"left":    memberUpdate("chat_member", "supergroup", "member", "left"),
+	}
+	for want, update := range cases {
+		res, err := bot.ApplyHandlers(nil, update)
+		if err != nil || res["route"] != want {
+			t.Errorf("got %v (%v), want %s", res["route"], err, want)
+		}
+	}
+	if _, err := bot.ApplyHandlers(nil, memberUpdate("chat_member", "supergroup", "member", "administrator")); err == nil {
+		t.Error("expected promotions to be unhandled")
+	}
+}
@@ -0,0 +1,103 @@
+package easytgbot
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// errorKey holds the error of a failed handler result.
+const errorKey = "\x00error"
+
+// ErrorHandlerFunc is a handler returning an error. It can be
+// registered like a HandlerFunc; its error fails the update, see Fail.
+type ErrorHandlerFunc func(interface{}, *Bot, Update) (JSONBody, error)
+
+// Fail returns a handler result failing the update with err:
+// ApplyHandlers returns err and reports it to the OnError handlers.
+func Fail(err error) JSONBody {
+	return JSONBody{errorKey: err}
+}
+
+// ResultError returns the error of a handler result, or nil.
+func ResultError(res JSONBody) error {
+	err, _ := res[errorKey].(error)
+	return err
+}
+
+// PanicError is a panic recovered from a handler.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover returns middleware turning panics of the next handlers into
+// a failed result with a PanicError and the stack trace, so outer
+// middleware sees the failure. ApplyHandlers recovers panics anyway.
+func Recover() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) (res JSONBody) {
+			defer func() {
+				if value := recover(); value != nil {
+					res = Fail(&PanicError{Value: value, Stack: debug.Stack()})
+				}
+			}()
+			return next(context, bot, update)
+		}
+	}
+}
+
+// OnError adds a handler for the errors of updates: failed handlers,
+// recovered panics, rejected callback data and undelivered results.
+// Without one, errors are logged.
+func (bot *Bot) OnError(handler func(err error, update Update)) {
+	bot.errorMutex.Lock()
+	defer bot.errorMutex.Unlock()
+	bot.errorHandlers = append(bot.errorHandlers, handler)
+}
+
+// reportError passes err to the OnError handlers.
+func (bot *Bot) reportError(err error, update Update) {
+	bot.errorMutex.Lock()
+	handlers := bot.errorHandlers
+	bot.errorMutex.Unlock()
+	if len(handlers) == 0 {
+		bot.logger.Error("update failed", "update_id", update.Get("update_id").Int(), "chat_id", update.ChatID(), "error", err)
+		return
+	}
+	for _, handler := range handlers {
+		handler(err, update)
+	}
+}
+
+// NotifyErrors sends the errors of updates to an admin chat with the
+// start of the update and, for panics, of the stack trace.
+func (bot *Bot) NotifyErrors(chatID int64) {
+	bot.OnError(func(err error, update Update) {
+		text := "<b>Error:</b> " + escapeHTML(trimText(err.Error(), 500))
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			text += "\n<pre>" + escapeHTML(trimText(string(panicErr.Stack), 1500)) + "</pre>"
+		}
+		if update.Raw != "" {
+			text += "\n<b>Update:</b>\n<pre>" + escapeHTML(trimText(update.Raw, 1500)) + "</pre>"
+		}
+		if _, sendErr := bot.SendMessage(chatID, text, JSONBody{"parse_mode": "HTML"}); sendErr != nil {
+			bot.logger.Error("notify errors", "chat_id", chatID, "error", sendErr, "reporting", err)
+		}
+	})
+}
+
+// trimText cuts text to at most limit runes.
+func trimText(text string, limit int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return string(runes[:limit]) + "…"
+}
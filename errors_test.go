@@ -0,0 +1,93 @@
+package easytgbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOnError(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	bot.Use(Recover())
+	failure := errors.New("database is down")
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) (JSONBody, error) {
+		if update.Get("message.text").String() == "fail" {
+			return nil, failure
+		}
+		return update.Reply("ok", nil), nil
+	})
+	bot.Handle("sticker", func(context interface{}, bot *Bot, update Update) JSONBody {
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+
+	reported := []error{}
+	bot.OnError(func(err error, update Update) {
+		reported = append(reported, err)
+	})
+	bot.NotifyErrors(42)
+
+	message := func(body string) Update {
+		return NewUpdate(`{"update_id":3,"message":{"message_id":1,"chat":{"id":7,"type":"private"},` + body + `}}`)
+	}
+
+	if res, err := bot.ApplyHandlers(nil, message(`"text":"hi"`)); err != nil || res["text"] != "ok" {
+		t.Errorf("got %v, %v", res, err)
+	}
+	if _, err := bot.ApplyHandlers(nil, message(`"text":"fail"`)); err != failure {
+		t.Errorf("got %v", err)
+	}
+	_, err := bot.ApplyHandlers(nil, message(`"sticker":{}`))
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || !strings.Contains(string(panicErr.Stack), "errors_test.go") {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := bot.ApplyHandlers(nil, message(`"photo":[]`)); err != ErrUnhandled {
+		t.Errorf("got %v", err)
+	}
+
+	if len(reported) != 2 || reported[0] != failure || reported[1] != err {
+		t.Errorf("reported %v", reported)
+	}
+	calls := api.Calls()
+	if len(calls) != 2 || calls[1].Params["chat_id"] != float64(42) {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+	text := calls[1].Params["text"].(string)
+	if !strings.Contains(text, "assignment to entry in nil map") || !strings.Contains(text, "<pre>") || !strings.Contains(text, `"sticker":{}`) {
+		t.Errorf("unexpected notification %s", text)
+	}
+
+	// panicking filters fail the update too
+	bot.Handle("voice", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{}
+	}, func(Update) bool {
+		panic("bad filter")
+	})
+	if _, err := bot.ApplyHandlers(nil, message(`"voice":{}`)); !errors.As(err, &panicErr) || panicErr.Value != "bad filter" {
+		t.Errorf("got %v", err)
+	}
+	if calls := api.Calls(); len(calls) != 3 || !strings.Contains(calls[2].Params["text"].(string), "<pre>") {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+func TestOnErrorWithoutRecover(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		panic("no recover")
+	})
+	reported := []error{}
+	bot.OnError(func(err error, update Update) {
+		reported = append(reported, err)
+	})
+	_, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"chat":{"id":7,"type":"private"},"text":"hi"}}`))
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "no recover" || len(reported) != 1 {
+		t.Errorf("got %v, reported %v", err, reported)
+	}
+	if stats := bot.Metrics().Snapshot().Routes["panic"]; stats.Errors != 1 {
+		t.Errorf("unexpected panic route metrics %+v", stats)
+	}
+}
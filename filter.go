@@ -0,0 +1,129 @@
+package easytgbot
+
+import (
+	"regexp"
+)
+
+// Filter reports whether a route should handle the update.
+type Filter func(Update) bool
+
+// And accepts the update when all filters accept it.
+func And(filters ...Filter) Filter {
+	return func(update Update) bool {
+		for _, filter := range filters {
+			if !filter(update) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or accepts the update when any filter accepts it.
+func Or(filters ...Filter) Filter {
+	return func(update Update) bool {
+		for _, filter := range filters {
+			if filter(update) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a filter.
+func Not(filter Filter) Filter {
+	return func(update Update) bool {
+		return !filter(update)
+	}
+}
+
+// ChatType accepts updates from chats of the given types,
+// e.g. "private", "group", "supergroup" or "channel".
+func ChatType(types ...string) Filter {
+	return func(update Update) bool {
+		chat, err := update.Chat()
+		if err != nil {
+			return false
+		}
+		chatType := chat.Get("type").String()
+		for _, t := range types {
+			if t == chatType {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FromUser accepts updates sent by one of the given users.
+func FromUser(userIDs ...int64) Filter {
+	return func(update Update) bool {
+		from, err := update.From()
+		if err != nil {
+			return false
+		}
+		id := from.Get("id").Int()
+		for _, userID := range userIDs {
+			if userID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasEntity accepts messages containing an entity of one of the given
+// types, e.g. "url", "mention" or "hashtag".
+func HasEntity(types ...string) Filter {
+	return func(update Update) bool {
+		for _, entity := range update.Entities() {
+			entityType := entity.Get("type").String()
+			for _, t := range types {
+				if t == entityType {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// IsReply accepts messages replying to another message.
+func IsReply(update Update) bool {
+	message, err := update.Message()
+	return err == nil && message.Get("reply_to_message").Exists()
+}
+
+// IsForward accepts forwarded messages.
+func IsForward(update Update) bool {
+	message, err := update.Message()
+	if err != nil {
+		return false
+	}
+	return message.Get("forward_origin").Exists() || message.Get("forward_date").Exists()
+}
+
+// Regex accepts messages whose text or caption matches the pattern.
+func Regex(pattern interface{}) Filter {
+	var re *regexp.Regexp
+	switch p := pattern.(type) {
+	case string:
+		re = regexp.MustCompile(p)
+	case *regexp.Regexp:
+		re = p
+	default:
+		panic("easytgbot: unsupported pattern")
+	}
+	return func(update Update) bool {
+		message, err := update.Message()
+		if err != nil {
+			return false
+		}
+		text := message.Get("text")
+		if !text.Exists() {
+			text = message.Get("caption")
+		}
+		return text.Exists() && re.MatchString(text.String())
+	}
+}
@@ -0,0 +1,63 @@
+package easytgbot
+
+import (
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	private := NewUpdate(`{"message":{"message_id":1,"from":{"id":7},"chat":{"id":7,"type":"private"},"text":"see https://t.me","entities":[{"offset":4,"length":12,"type":"url"}]}}`)
+	group := NewUpdate(`{"message":{"message_id":2,"from":{"id":8},"chat":{"id":-100,"type":"supergroup"},"text":"hello","reply_to_message":{"message_id":1},"forward_date":1587018473}}`)
+
+	cases := []struct {
+		name   string
+		filter Filter
+		update Update
+		want   bool
+	}{
+		{"chat type", ChatType("private"), private, true},
+		{"chat type mismatch", ChatType("group", "supergroup"), private, false},
+		{"from user", FromUser(1, 7), private, true},
+		{"has entity", HasEntity("url"), private, true},
+		{"is reply", IsReply, group, true},
+		{"not reply", Not(IsReply), private, true},
+		{"is forward", IsForward, group, true},
+		{"regex", Regex(`^hel+o$`), group, true},
+		{"and", And(ChatType("supergroup"), FromUser(7)), group, false},
+		{"or", Or(ChatType("private"), FromUser(8)), group, true},
+	}
+	for _, c := range cases {
+		if got := c.filter(c.update); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFilteredRoutes(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	reply := func(name string) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			return JSONBody{"route": name}
+		}
+	}
+	bot.Handle("text", reply("fallback"))
+	bot.Handle("text", reply("private"), ChatType("private"))
+	bot.Handle("text", reply("admin"), FromUser(8))
+	bot.Action("vote", reply("vote"), FromUser(7))
+
+	cases := map[string]string{
+		`{"message":{"chat":{"id":7,"type":"private"},"from":{"id":7},"text":"hi"}}`: "private",
+		`{"message":{"chat":{"id":-1,"type":"group"},"from":{"id":8},"text":"hi"}}`:  "admin",
+		`{"message":{"chat":{"id":-1,"type":"group"},"from":{"id":9},"text":"hi"}}`:  "fallback",
+		`{"callback_query":{"id":"1","from":{"id":7},"data":"vote"}}`:                "vote",
+	}
+	for body, want := range cases {
+		res, err := bot.ApplyHandlers(nil, NewUpdate(body))
+		if err != nil || res["route"] != want {
+			t.Errorf("%s: got %v (%v), want %s", body, res["route"], err, want)
+		}
+	}
+
+	if _, err := bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"2","from":{"id":8},"data":"vote"}}`)); err == nil {
+		t.Error("expected filtered action to be rejected")
+	}
+}
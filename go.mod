@@ -1,6 +1,6 @@
 module github.com/mylukin/easytgbot
 
-go 1.14
+go 1.16
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,67 @@
+package easytgbot
+
+// Group is a set of routes sharing middleware. Its middleware runs inside
+// the bot's global chain, and nested groups run inside their parent's.
+type Group struct {
+	bot        *Bot
+	parent     *Group
+	middleware []MiddlewareFunc
+}
+
+// Group creates a route group whose handlers get the given middleware
+// on top of the bot's global middleware.
+func (bot *Bot) Group(middleware ...MiddlewareFunc) *Group {
+	return &Group{
+		bot:        bot,
+		middleware: middleware,
+	}
+}
+
+// Group creates a nested group inheriting the middleware of g.
+func (g *Group) Group(middleware ...MiddlewareFunc) *Group {
+	return &Group{
+		bot:        g.bot,
+		parent:     g,
+		middleware: middleware,
+	}
+}
+
+// Use appends middleware to the group.
+func (g *Group) Use(middleware ...MiddlewareFunc) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Handle registers a command or update type handler in the group.
+func (g *Group) Handle(endpoint string, handler interface{}, filters ...Filter) {
+	g.bot.Handle(endpoint, g.wrap(handler), filters...)
+}
+
+// Action registers a callback query handler in the group.
+func (g *Group) Action(endpoint interface{}, handler interface{}, filters ...Filter) {
+	g.bot.Action(endpoint, g.wrap(handler), filters...)
+}
+
+// Inline registers an inline query handler in the group.
+func (g *Group) Inline(endpoint interface{}, handler interface{}, filters ...Filter) {
+	g.bot.Inline(endpoint, g.wrap(handler), filters...)
+}
+
+// chain returns the middleware of g and its parents, outermost first.
+func (g *Group) chain() []MiddlewareFunc {
+	if g.parent == nil {
+		return g.middleware
+	}
+	return append(append([]MiddlewareFunc{}, g.parent.chain()...), g.middleware...)
+}
+
+// wrap applies the group chain at call time, so middleware added with
+// Use after registration still takes effect.
+func (g *Group) wrap(handler interface{}) HandlerFunc {
+	h, ok := toHandlerFunc(handler)
+	if !ok {
+		panic("easytgbot: unsupported handler")
+	}
+	return func(context interface{}, bot *Bot, update Update) JSONBody {
+		return applyMiddleware(h, g.chain()...)(context, bot, update)
+	}
+}
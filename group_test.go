@@ -0,0 +1,86 @@
+package easytgbot
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func tagMiddleware(tag string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			res := next(context, bot, update)
+			res["trace"] = tag + "," + res["trace"].(string)
+			return res
+		}
+	}
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	bot.Use(tagMiddleware("global"))
+
+	admin := bot.Group(tagMiddleware("admin"))
+	owner := admin.Group(tagMiddleware("owner"))
+	owner.Handle("/ban", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"trace": "handler"}
+	})
+	admin.Use(tagMiddleware("late"))
+
+	update := NewUpdate(`{"update_id":1,"message":{"message_id":1,"chat":{"id":1,"type":"private"},"text":"/ban","entities":[{"offset":0,"length":4,"type":"bot_command"}]}}`)
+	res, err := bot.ApplyHandlers(nil, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace := res["trace"].(string); trace != "global,admin,late,owner,handler" {
+		t.Errorf("unexpected middleware order: %s", trace)
+	}
+
+	// the group does not leak into global handlers
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"trace": "text"}
+	})
+	res, _ = bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":2,"chat":{"id":1,"type":"private"},"text":"hi"}}`))
+	if trace := res["trace"].(string); strings.Contains(trace, "admin") {
+		t.Errorf("group middleware applied globally: %s", trace)
+	}
+
+	admin.Inline(regexp.MustCompile(`^ban `), func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"trace": "inline"}
+	})
+	res, err = bot.ApplyHandlers(nil, NewUpdate(`{"inline_query":{"id":"q1","from":{"id":7},"query":"ban bob","offset":""}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace := res["trace"].(string); trace != "global,admin,late,inline" {
+		t.Errorf("unexpected inline middleware order: %s", trace)
+	}
+}
+
+func TestUseAll(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	seen := []string{}
+	count := func(tag string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(context interface{}, bot *Bot, update Update) JSONBody {
+				seen = append(seen, tag)
+				return next(context, bot, update)
+			}
+		}
+	}
+	bot.Use(count("use"))
+	bot.UseAll(count("all"))
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{}
+	})
+
+	if _, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"chat":{"id":1,"type":"private"},"text":"hi"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":2,"chat":{"id":1,"type":"private"},"sticker":{"file_id":"x"}}}`)); err != ErrUnhandled {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if strings.Join(seen, ",") != "all,use,all" {
+		t.Errorf("unexpected middleware runs %v", seen)
+	}
+}
@@ -0,0 +1,81 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// apiCall is a request received by the fake Bot API.
+type apiCall struct {
+	Method string
+	Params JSONBody
+}
+
+// fakeAPI records Bot API calls and answers them with reply.
+type fakeAPI struct {
+	mutex sync.Mutex
+	calls []apiCall
+	reply func(method string, params JSONBody) (interface{}, *Error)
+}
+
+func (api *fakeAPI) Calls() []apiCall {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	return append([]apiCall{}, api.calls...)
+}
+
+// newTestBot returns a bot talking to a fake Bot API.
+func newTestBot(t *testing.T, opts Settings) (*Bot, *fakeAPI) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		params := JSONBody{}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			r.ParseMultipartForm(1 << 20)
+			for key, values := range r.MultipartForm.Value {
+				params[key] = values[0]
+			}
+			for key := range r.MultipartForm.File {
+				params[key] = "file"
+			}
+		} else {
+			data, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(data, &params)
+		}
+		api.mutex.Lock()
+		api.calls = append(api.calls, apiCall{method, params})
+		api.mutex.Unlock()
+
+		var result interface{} = true
+		var apiErr *Error
+		if api.reply != nil {
+			result, apiErr = api.reply(method, params)
+		}
+		if apiErr != nil {
+			body := JSONBody{
+				"ok":          false,
+				"error_code":  apiErr.Code,
+				"description": apiErr.Message,
+			}
+			if apiErr.Parameters.Raw != "" {
+				body["parameters"] = json.RawMessage(apiErr.Parameters.Raw)
+			}
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+		json.NewEncoder(w).Encode(JSONBody{"ok": true, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	opts.Endpoint = server.URL + "/bot%s/%s"
+	bot, err := New("123:token", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot, api
+}
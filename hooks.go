@@ -0,0 +1,94 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/tidwall/gjson"
+)
+
+// BeforeRequestFunc runs before a Bot API call with a copy of its
+// params. It returns the params to send, or nil to keep them; a
+// "method" key sends another method. Returning an error fails the
+// call, unless it is a ShortCircuit.
+type BeforeRequestFunc func(method string, params JSONBody) (JSONBody, error)
+
+// AfterRequestFunc runs after a Bot API call, or its short circuit,
+// with the method and params sent, the result and the error.
+type AfterRequestFunc func(method string, params JSONBody, res Update, err error)
+
+// BeforeRequest adds a hook run before every API call, in order.
+// Long texts are split before the hooks run on each part; params the
+// hooks make too long are split and sent without running them again.
+func (bot *Bot) BeforeRequest(hook BeforeRequestFunc) {
+	bot.hookMutex.Lock()
+	defer bot.hookMutex.Unlock()
+	bot.beforeHooks = append(bot.beforeHooks, hook)
+}
+
+// AfterRequest adds a hook run after every API call, in order.
+func (bot *Bot) AfterRequest(hook AfterRequestFunc) {
+	bot.hookMutex.Lock()
+	defer bot.hookMutex.Unlock()
+	bot.afterHooks = append(bot.afterHooks, hook)
+}
+
+// shortCircuit carries the result of a call a hook answered itself.
+type shortCircuit struct {
+	result Update
+}
+
+func (s shortCircuit) Error() string {
+	return "request short-circuited"
+}
+
+// ShortCircuit returns the error a BeforeRequest hook returns to skip
+// the call: MakeRequest returns result, converted through JSON, and
+// no error. Later hooks do not run.
+func ShortCircuit(result interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return shortCircuit{Update{gjson.ParseBytes(data)}}
+}
+
+// beforeRequest runs the BeforeRequest hooks and returns the call to
+// make, or its result or error when a hook ends it.
+func (bot *Bot) beforeRequest(method string, params JSONBody) (string, JSONBody, *Update, error) {
+	bot.hookMutex.RLock()
+	hooks := bot.beforeHooks
+	bot.hookMutex.RUnlock()
+	if len(hooks) == 0 {
+		return method, params, nil, nil
+	}
+
+	params = mergeJSON(JSONBody{}, params)
+	for _, hook := range hooks {
+		rewritten, err := hook(method, mergeJSON(JSONBody{}, params))
+		if err != nil {
+			var short shortCircuit
+			if errors.As(err, &short) {
+				return method, params, &short.result, nil
+			}
+			return method, params, nil, err
+		}
+		if rewritten != nil {
+			if name, ok := rewritten["method"].(string); ok && name != "" {
+				method = name
+			}
+			_, params = splitMethod(rewritten)
+		}
+	}
+	return method, params, nil, nil
+}
+
+// afterRequest runs the AfterRequest hooks.
+func (bot *Bot) afterRequest(method string, params JSONBody, res Update, err error) {
+	bot.hookMutex.RLock()
+	hooks := bot.afterHooks
+	bot.hookMutex.RUnlock()
+	for _, hook := range hooks {
+		hook(method, params, res, err)
+	}
+}
@@ -0,0 +1,103 @@
+package easytgbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequestHooks(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		return JSONBody{"message_id": 9}, nil
+	}
+	denied := errors.New("denied")
+	bot.BeforeRequest(func(method string, params JSONBody) (JSONBody, error) {
+		switch {
+		case params["chat_id"] == int64(13):
+			return nil, denied
+		case method == "getMe":
+			return nil, ShortCircuit(JSONBody{"id": 1, "username": "cached"})
+		case method == "sendMessage":
+			params["disable_notification"] = true
+			return params, nil
+		case method == "forwardMessage":
+			params["method"] = "copyMessage"
+			return params, nil
+		}
+		return nil, nil
+	})
+	audit := []string{}
+	bot.AfterRequest(func(method string, params JSONBody, res Update, err error) {
+		entry := method
+		if err != nil {
+			entry += " " + err.Error()
+		}
+		audit = append(audit, entry)
+	})
+
+	extra := JSONBody{"reply_markup": JSONBody{}}
+	res, err := bot.SendMessage(7, "hi", extra)
+	if err != nil || res.Get("message_id").Int() != 9 {
+		t.Errorf("got %v, %v", res, err)
+	}
+	if _, ok := extra["disable_notification"]; ok {
+		t.Error("hook changed the params of the caller")
+	}
+	if _, err := bot.ForwardMessage(7, 8, 1, nil); err != nil {
+		t.Error(err)
+	}
+	me, err := bot.GetMe()
+	if err != nil || me.Get("username").String() != "cached" {
+		t.Errorf("got %v, %v", me, err)
+	}
+	if _, err := bot.SendMessage(13, "hi", nil); err != denied {
+		t.Errorf("got %v", err)
+	}
+
+	calls := api.Calls()
+	if len(calls) != 2 || calls[0].Params["disable_notification"] != true || calls[1].Method != "copyMessage" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+	want := []string{"sendMessage", "copyMessage", "getMe", "sendMessage denied"}
+	if len(audit) != len(want) {
+		t.Fatalf("got audit %v", audit)
+	}
+	for i := range want {
+		if audit[i] != want[i] {
+			t.Errorf("got audit %v, want %v", audit, want)
+			break
+		}
+	}
+}
+
+func TestRequestHooksSplit(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	footer := strings.Repeat("-", 100)
+	bot.BeforeRequest(func(method string, params JSONBody) (JSONBody, error) {
+		if method == "sendMessage" {
+			params["text"] = params["text"].(string) + "\n" + footer
+		}
+		return params, nil
+	})
+	sent := 0
+	bot.AfterRequest(func(method string, params JSONBody, res Update, err error) {
+		sent++
+	})
+
+	if _, err := bot.SendMessage(7, strings.Repeat("a", MessageLimit-50), nil); err != nil {
+		t.Fatal(err)
+	}
+	calls := api.Calls()
+	if len(calls) != 2 || sent != 2 {
+		t.Fatalf("got %d calls and %d after hooks, want 2", len(calls), sent)
+	}
+	for _, call := range calls {
+		if utf16Len(call.Params["text"].(string)) > MessageLimit {
+			t.Errorf("rewritten text was sent oversized")
+		}
+	}
+	if !strings.HasSuffix(calls[1].Params["text"].(string), footer) {
+		t.Errorf("footer was not sent once at the end")
+	}
+}
@@ -0,0 +1,624 @@
+package easytgbot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pluralCategories are the CLDR plural categories.
+var pluralCategories = map[string]bool{
+	"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true,
+}
+
+// PluralRule selects the plural category of a count.
+type PluralRule func(n int64) string
+
+type pluralRule struct {
+	forms []string
+	rule  PluralRule
+}
+
+var (
+	pluralOneOther = pluralRule{[]string{"one", "other"}, func(n int64) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+	pluralZeroOne = pluralRule{[]string{"one", "other"}, func(n int64) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	}}
+	pluralNone = pluralRule{[]string{"other"}, func(n int64) string {
+		return "other"
+	}}
+	pluralSlavic = pluralRule{[]string{"one", "few", "many"}, func(n int64) string {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		}
+		return "many"
+	}}
+	pluralPolish = pluralRule{[]string{"one", "few", "many"}, func(n int64) string {
+		switch {
+		case n == 1:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		}
+		return "many"
+	}}
+	pluralCzech = pluralRule{[]string{"one", "few", "other"}, func(n int64) string {
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		}
+		return "other"
+	}}
+	pluralArabic = pluralRule{[]string{"zero", "one", "two", "few", "many", "other"}, func(n int64) string {
+		switch {
+		case n == 0:
+			return "zero"
+		case n == 1:
+			return "one"
+		case n == 2:
+			return "two"
+		case n%100 >= 3 && n%100 <= 10:
+			return "few"
+		case n%100 >= 11:
+			return "many"
+		}
+		return "other"
+	}}
+)
+
+// pluralRules by base language; others use one/other.
+var pluralRules = map[string]pluralRule{
+	"fr": pluralZeroOne, "pt": pluralZeroOne,
+	"zh": pluralNone, "ja": pluralNone, "ko": pluralNone, "th": pluralNone,
+	"vi": pluralNone, "id": pluralNone, "ms": pluralNone,
+	"ru": pluralSlavic, "uk": pluralSlavic, "be": pluralSlavic,
+	"pl": pluralPolish,
+	"cs": pluralCzech, "sk": pluralCzech,
+	"ar": pluralArabic,
+}
+
+// I18n translates messages by the language of the user.
+// Catalogs map keys to a text or to plural forms by CLDR category,
+// e.g. {"apples": {"one": "{count} apple", "other": "{count} apples"}};
+// nested objects of other keys become dotted keys. Placeholders like
+// {name} are replaced by the arguments, and the "count" argument
+// selects the plural form.
+type I18n struct {
+	// Default is the locale used when nothing better matches.
+	Default string
+	// Overrides keeps locales chosen by users, keyed by user id.
+	Overrides SessionStore
+
+	catalogs map[string]map[string]map[string]string
+	rules    map[string]pluralRule
+	mutex    sync.RWMutex
+}
+
+// NewI18n creates a translator falling back to defaultLocale.
+func NewI18n(defaultLocale string) *I18n {
+	return &I18n{
+		Default:   normalizeLocale(defaultLocale),
+		Overrides: NewMemoryStore(0),
+		catalogs:  make(map[string]map[string]map[string]string),
+		rules:     make(map[string]pluralRule),
+	}
+}
+
+// normalizeLocale turns "pt_BR" into "pt-br".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
+
+// PluralRule sets the plural rule of a language; forms lists its
+// categories in the order of gettext plural indexes.
+func (i *I18n) PluralRule(lang string, forms []string, rule PluralRule) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.rules[normalizeLocale(lang)] = pluralRule{forms, rule}
+}
+
+func (i *I18n) pluralRule(locale string) pluralRule {
+	for _, l := range []string{locale, strings.SplitN(locale, "-", 2)[0]} {
+		if r, ok := i.rules[l]; ok {
+			return r
+		}
+		if r, ok := pluralRules[l]; ok {
+			return r
+		}
+	}
+	return pluralOneOther
+}
+
+// Set adds a message to a catalog with its forms by plural category;
+// "other" serves counts without a form of their own.
+func (i *I18n) Set(locale string, key string, forms map[string]string) {
+	locale = normalizeLocale(locale)
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	catalog, ok := i.catalogs[locale]
+	if !ok {
+		catalog = make(map[string]map[string]string)
+		i.catalogs[locale] = catalog
+	}
+	catalog[key] = forms
+}
+
+// Locales returns the loaded locales.
+func (i *I18n) Locales() []string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	locales := []string{}
+	for locale := range i.catalogs {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// LoadDir loads every catalog file of a directory.
+func (i *I18n) LoadDir(dir string) error {
+	return i.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS loads every catalog file of dir in fsys, e.g. an embed.FS.
+// Files are named after their locale: en.json, pt-BR.yaml, ru.po.
+func (i *I18n) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := path.Ext(entry.Name())
+		locale := strings.TrimSuffix(entry.Name(), ext)
+		var load func(string, []byte) error
+		switch strings.ToLower(ext) {
+		case ".json":
+			load = i.LoadJSON
+		case ".yaml", ".yml":
+			load = i.LoadYAML
+		case ".po":
+			load = i.LoadPO
+		default:
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := load(locale, data); err != nil {
+			return fmt.Errorf("i18n: %s: %s", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// LoadJSON loads a JSON catalog.
+func (i *I18n) LoadJSON(locale string, data []byte) error {
+	tree := map[string]interface{}{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+	return i.loadTree(locale, "", tree)
+}
+
+// loadTree adds the messages of a decoded catalog.
+func (i *I18n) loadTree(locale string, prefix string, tree map[string]interface{}) error {
+	for key, value := range tree {
+		switch v := value.(type) {
+		case string:
+			i.Set(locale, prefix+key, map[string]string{"other": v})
+		case map[string]interface{}:
+			plural := len(v) > 0
+			forms := map[string]string{}
+			for category, form := range v {
+				text, ok := form.(string)
+				if !ok || !pluralCategories[category] {
+					plural = false
+					break
+				}
+				forms[category] = text
+			}
+			if plural {
+				i.Set(locale, prefix+key, forms)
+			} else if err := i.loadTree(locale, prefix+key+".", v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported value for %s%s", prefix, key)
+		}
+	}
+	return nil
+}
+
+// LoadYAML loads a YAML catalog. Only the subset used by catalogs is
+// supported: nested mappings of single-line plain or quoted strings,
+// block (| or >) strings, and comments. Other syntax, such as flow
+// collections, sequences, anchors, tags and multi-line plain or
+// quoted strings, is rejected with an error.
+func (i *I18n) LoadYAML(locale string, data []byte) error {
+	tree, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	return i.loadTree(locale, "", tree)
+}
+
+type yamlLevel struct {
+	indent int
+	node   map[string]interface{}
+}
+
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	stack := []yamlLevel{{-1, root}}
+	scalar := -1
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		content := strings.TrimSpace(line)
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case strings.HasPrefix(strings.TrimLeft(line, " "), "\t"):
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", n+1)
+		case scalar >= 0 && indent > scalar:
+			return nil, fmt.Errorf("line %d: multi-line strings must use a block scalar (|)", n+1)
+		case content == "-" || strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "? "):
+			return nil, fmt.Errorf("line %d: unsupported YAML syntax %q", n+1, content)
+		}
+		scalar = -1
+		colon := yamlColon(content)
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		key, err := yamlScalar(content[:colon])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", n+1, err)
+		}
+		value := strings.TrimSpace(content[colon+1:])
+
+		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		node := stack[len(stack)-1].node
+
+		switch {
+		case value == "" || strings.HasPrefix(value, "#"):
+			child := map[string]interface{}{}
+			node[key] = child
+			stack = append(stack, yamlLevel{indent, child})
+		case value == "|" || value == "|-" || value == ">" || value == ">-":
+			block := []string{}
+			blockIndent := -1
+			for n+1 < len(lines) {
+				next := lines[n+1]
+				if strings.TrimSpace(next) == "" {
+					block = append(block, "")
+					n++
+					continue
+				}
+				nextIndent := len(next) - len(strings.TrimLeft(next, " "))
+				if nextIndent <= indent {
+					break
+				}
+				if blockIndent < 0 {
+					blockIndent = nextIndent
+				}
+				if nextIndent < blockIndent {
+					break
+				}
+				block = append(block, next[blockIndent:])
+				n++
+			}
+			for len(block) > 0 && block[len(block)-1] == "" {
+				block = block[:len(block)-1]
+			}
+			sep := "\n"
+			if value[0] == '>' {
+				sep = " "
+			}
+			text := strings.Join(block, sep)
+			if !strings.HasSuffix(value, "-") {
+				text += "\n"
+			}
+			node[key] = text
+			scalar = indent
+		case strings.ContainsRune("[{&*!|>%@`", rune(value[0])):
+			return nil, fmt.Errorf("line %d: unsupported YAML syntax %q", n+1, value)
+		default:
+			scalar = indent
+			text, err := yamlScalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", n+1, err)
+			}
+			node[key] = text
+		}
+	}
+	return root, nil
+}
+
+// yamlColon finds the colon ending the key of a mapping line.
+func yamlColon(line string) int {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i+1 == len(line) || line[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+// yamlScalar decodes a plain or quoted scalar.
+func yamlScalar(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := 1
+		for ; end < len(value); end++ {
+			if value[end] == '\\' {
+				end++
+			} else if value[end] == '"' {
+				break
+			}
+		}
+		if end >= len(value) {
+			return "", fmt.Errorf("unterminated string %s", value)
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		var text strings.Builder
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\'' {
+				if i+1 < len(value) && value[i+1] == '\'' {
+					text.WriteByte('\'')
+					i++
+					continue
+				}
+				return text.String(), nil
+			}
+			text.WriteByte(value[i])
+		}
+		return "", fmt.Errorf("unterminated string %s", value)
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// LoadPO loads a gettext PO catalog; msgids are the keys, prefixed
+// by "msgctxt." when present, and the msgstr[n] plural forms follow
+// the order of the language's plural categories. Fuzzy and
+// untranslated entries are skipped.
+func (i *I18n) LoadPO(locale string, data []byte) error {
+	i.mutex.RLock()
+	forms := i.pluralRule(normalizeLocale(locale)).forms
+	i.mutex.RUnlock()
+
+	var (
+		fuzzy   bool
+		context string
+		id      string
+		plural  string
+		strs    map[int]*string
+		field   *string
+	)
+	flush := func() {
+		message := map[string]string{}
+		for n, text := range strs {
+			switch {
+			case *text == "":
+			case plural == "":
+				message["other"] = *text
+			case n < len(forms):
+				message[forms[n]] = *text
+			}
+		}
+		if id != "" && !fuzzy && len(message) > 0 {
+			key := id
+			if context != "" {
+				key = context + "." + id
+			}
+			i.Set(locale, key, message)
+		}
+		fuzzy, context, id, plural, strs, field = false, "", "", "", map[int]*string{}, nil
+	}
+	flush()
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			if len(strs) > 0 {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return fmt.Errorf("line %d: unexpected string", n)
+			}
+			text, err := strconv.Unquote(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			*field += text
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: unexpected %s", n, line)
+		}
+		text, err := strconv.Unquote(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("line %d: %s", n, err)
+		}
+		switch keyword := parts[0]; {
+		case keyword == "msgctxt":
+			if len(strs) > 0 {
+				flush()
+			}
+			context = text
+			field = &context
+		case keyword == "msgid":
+			if len(strs) > 0 {
+				flush()
+			}
+			id = text
+			field = &id
+		case keyword == "msgid_plural":
+			plural = text
+			field = &plural
+		case keyword == "msgstr" || strings.HasPrefix(keyword, "msgstr["):
+			index := 0
+			if keyword != "msgstr" {
+				index, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(keyword, "msgstr["), "]"))
+				if err != nil {
+					return fmt.Errorf("line %d: %s", n, err)
+				}
+			}
+			field = &text
+			strs[index] = field
+		default:
+			return fmt.Errorf("line %d: unknown keyword %s", n, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+// Translate returns the message key of a locale with args replacing
+// its placeholders. Missing messages fall back to the base language,
+// then the default locale, then the key itself.
+func (i *I18n) Translate(locale string, key string, args JSONBody) string {
+	locale = normalizeLocale(locale)
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	var (
+		forms map[string]string
+		found string
+	)
+	for _, l := range []string{locale, strings.SplitN(locale, "-", 2)[0], i.Default, strings.SplitN(i.Default, "-", 2)[0]} {
+		if message, ok := i.catalogs[l][key]; ok {
+			forms, found = message, l
+			break
+		}
+	}
+	if forms == nil {
+		return formatMessage(key, args)
+	}
+
+	text, ok := forms["other"]
+	if count, has := args["count"]; has {
+		n, err := strconv.ParseInt(fmt.Sprint(count), 10, 64)
+		if err == nil {
+			if form, exists := forms[i.pluralRule(found).rule(n)]; exists {
+				text, ok = form, true
+			}
+		}
+	}
+	if !ok {
+		for _, form := range forms {
+			text = form
+			break
+		}
+	}
+	return formatMessage(text, args)
+}
+
+// formatMessage replaces {name} placeholders; unknown ones are kept.
+func formatMessage(text string, args JSONBody) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// overrideKey is the Overrides key of a user.
+func overrideKey(userID int64) string {
+	return "locale:" + strconv.FormatInt(userID, 10)
+}
+
+// SetLocale overrides the locale of a user; an empty locale removes
+// the override.
+func (i *I18n) SetLocale(userID int64, locale string) error {
+	if locale == "" {
+		return i.Overrides.Delete(overrideKey(userID))
+	}
+	return i.Overrides.Set(overrideKey(userID), JSONBody{"locale": normalizeLocale(locale)})
+}
+
+// Locale returns the locale of the user of an update: their override,
+// else their Telegram language_code, else the default.
+func (i *I18n) Locale(update Update) string {
+	userID := update.FromID()
+	if userID != 0 && i.Overrides != nil {
+		data, err := i.Overrides.Get(overrideKey(userID))
+		if err == nil {
+			if locale, ok := data["locale"].(string); ok && locale != "" {
+				return locale
+			}
+		}
+	}
+	from, err := update.From()
+	if err == nil {
+		if code := from.Get("language_code").String(); code != "" {
+			return normalizeLocale(code)
+		}
+	}
+	return i.Default
+}
+
+// T translates key for the user of an update.
+func (i *I18n) T(update Update, key string, args JSONBody) string {
+	return i.Translate(i.Locale(update), key, args)
+}
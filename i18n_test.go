@@ -0,0 +1,101 @@
+package easytgbot
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestI18n(t *testing.T) {
+	catalogs := fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{
+			"hello": "Hello, {name}!",
+			"apples": {"one": "{count} apple", "other": "{count} apples"},
+			"menu": {"title": "Menu"}
+		}`)},
+		"locales/ru.yaml": {Data: []byte(`# Russian
+hello: "Привет, {name}!"
+apples:
+  one: '{count} яблоко'
+  few: "{count} яблока"
+  many: "{count} яблок"
+menu:
+  title: |
+    Меню
+`)},
+		"locales/uk.po": {Data: []byte(`msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3;\n"
+
+#, fuzzy
+msgid "hello"
+msgstr "Привіт"
+
+msgid "apples"
+msgid_plural "apples"
+msgstr[0] "{count} яблуко"
+msgstr[1] "{count} яблука"
+msgstr[2] "{count} "
+"яблук"
+`)},
+	}
+	i18n := NewI18n("en")
+	if err := i18n.LoadFS(catalogs, "locales"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		locale, key string
+		args        JSONBody
+		want        string
+	}{
+		{"en", "hello", JSONBody{"name": "Ann"}, "Hello, Ann!"},
+		{"en-GB", "apples", JSONBody{"count": 1}, "1 apple"},
+		{"en", "apples", JSONBody{"count": 2}, "2 apples"},
+		{"en", "menu.title", nil, "Menu"},
+		{"ru", "hello", JSONBody{"name": "Аня"}, "Привет, Аня!"},
+		{"ru", "apples", JSONBody{"count": 21}, "21 яблоко"},
+		{"ru", "apples", JSONBody{"count": 3}, "3 яблока"},
+		{"ru", "apples", JSONBody{"count": 11}, "11 яблок"},
+		{"ru", "menu.title", nil, "Меню\n"},
+		{"uk", "apples", JSONBody{"count": 5}, "5 яблук"},
+		{"uk", "hello", JSONBody{"name": "Ann"}, "Hello, Ann!"},
+		{"de", "missing", nil, "missing"},
+	}
+	for _, test := range tests {
+		if got := i18n.Translate(test.locale, test.key, test.args); got != test.want {
+			t.Errorf("%s %s: got %q, want %q", test.locale, test.key, got, test.want)
+		}
+	}
+
+	update := NewUpdate(`{"message":{"message_id":1,"from":{"id":7,"language_code":"ru"},"chat":{"id":7,"type":"private"},"text":"hi"}}`)
+	if got := update.ReplyT(i18n, "hello", JSONBody{"name": "Ann"}, nil); got["text"] != "Привет, Ann!" {
+		t.Errorf("got %v", got)
+	}
+	if err := i18n.SetLocale(7, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if got := i18n.T(update, "hello", JSONBody{"name": "Ann"}); got != "Hello, Ann!" {
+		t.Errorf("override got %q", got)
+	}
+	i18n.SetLocale(7, "")
+	if got := i18n.Locale(update); got != "ru" {
+		t.Errorf("got locale %s", got)
+	}
+}
+
+func TestI18nYAMLUnsupported(t *testing.T) {
+	for _, data := range []string{
+		"hello: {one: apple, other: apples}\n",
+		"hello: [apple, apples]\n",
+		"hello:\n  - apple\n",
+		"hello: first line\n  second: line\n",
+		"hello: &greeting Hi\n",
+		"hello: !!str Hi\n",
+		"hello: |+\n  Hi\n",
+		"hello:\n\tone: Hi\n",
+	} {
+		if err := NewI18n("en").LoadYAML("en", []byte(data)); err == nil {
+			t.Errorf("LoadYAML(%q) = nil, want error", data)
+		}
+	}
+}
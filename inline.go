@@ -0,0 +1,170 @@
+package easytgbot
+
+import (
+	"strconv"
+)
+
+// AnswerInlineQuery see https://core.telegram.org/bots/api#answerinlinequery
+func (bot *Bot) AnswerInlineQuery(queryID string, results []JSONBody, extra JSONBody) (Update, error) {
+	return bot.MakeRequest("answerInlineQuery", mergeJSON(JSONBody{
+		"inline_query_id": queryID,
+		"results":         results,
+	}, extra))
+}
+
+// AnswerInlineQuery answers the inline query of the update.
+func (update Update) AnswerInlineQuery(results []JSONBody, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"method":          "answerInlineQuery",
+		"inline_query_id": update.Get("inline_query.id").String(),
+		"results":         results,
+	}, extra)
+}
+
+// PageInlineResults returns the page of results starting at the inline
+// query offset, and the offset of the next page, which is empty on the
+// last page.
+func PageInlineResults(results []JSONBody, offset string, size int) ([]JSONBody, string) {
+	start, _ := strconv.Atoi(offset)
+	if start < 0 || start > len(results) {
+		start = len(results)
+	}
+	end := start + size
+	if size <= 0 || end >= len(results) {
+		return results[start:], ""
+	}
+	return results[start:end], strconv.Itoa(end)
+}
+
+// NewInlineArticle see https://core.telegram.org/bots/api#inlinequeryresultarticle
+func NewInlineArticle(id string, title string, content JSONBody, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":                  "article",
+		"id":                    id,
+		"title":                 title,
+		"input_message_content": content,
+	}, extra)
+}
+
+// NewInlinePhoto see https://core.telegram.org/bots/api#inlinequeryresultphoto
+func NewInlinePhoto(id string, photoURL string, thumbnailURL string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":          "photo",
+		"id":            id,
+		"photo_url":     photoURL,
+		"thumbnail_url": thumbnailURL,
+	}, extra)
+}
+
+// NewInlineGif see https://core.telegram.org/bots/api#inlinequeryresultgif
+func NewInlineGif(id string, gifURL string, thumbnailURL string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":          "gif",
+		"id":            id,
+		"gif_url":       gifURL,
+		"thumbnail_url": thumbnailURL,
+	}, extra)
+}
+
+// NewInlineVideo see https://core.telegram.org/bots/api#inlinequeryresultvideo
+func NewInlineVideo(id string, videoURL string, mimeType string, thumbnailURL string, title string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":          "video",
+		"id":            id,
+		"video_url":     videoURL,
+		"mime_type":     mimeType,
+		"thumbnail_url": thumbnailURL,
+		"title":         title,
+	}, extra)
+}
+
+// NewInlineDocument see https://core.telegram.org/bots/api#inlinequeryresultdocument
+func NewInlineDocument(id string, title string, documentURL string, mimeType string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":         "document",
+		"id":           id,
+		"title":        title,
+		"document_url": documentURL,
+		"mime_type":    mimeType,
+	}, extra)
+}
+
+// NewInlineCachedPhoto see https://core.telegram.org/bots/api#inlinequeryresultcachedphoto
+func NewInlineCachedPhoto(id string, fileID string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":          "photo",
+		"id":            id,
+		"photo_file_id": fileID,
+	}, extra)
+}
+
+// NewInlineCachedGif see https://core.telegram.org/bots/api#inlinequeryresultcachedgif
+func NewInlineCachedGif(id string, fileID string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":        "gif",
+		"id":          id,
+		"gif_file_id": fileID,
+	}, extra)
+}
+
+// NewInlineCachedVideo see https://core.telegram.org/bots/api#inlinequeryresultcachedvideo
+func NewInlineCachedVideo(id string, fileID string, title string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":          "video",
+		"id":            id,
+		"video_file_id": fileID,
+		"title":         title,
+	}, extra)
+}
+
+// NewInlineCachedDocument see https://core.telegram.org/bots/api#inlinequeryresultcacheddocument
+func NewInlineCachedDocument(id string, title string, fileID string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":             "document",
+		"id":               id,
+		"title":            title,
+		"document_file_id": fileID,
+	}, extra)
+}
+
+// NewInlineCachedSticker see https://core.telegram.org/bots/api#inlinequeryresultcachedsticker
+func NewInlineCachedSticker(id string, fileID string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"type":            "sticker",
+		"id":              id,
+		"sticker_file_id": fileID,
+	}, extra)
+}
+
+// NewInputTextContent see https://core.telegram.org/bots/api#inputtextmessagecontent
+func NewInputTextContent(text string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"message_text": text,
+	}, extra)
+}
+
+// NewInputLocationContent see https://core.telegram.org/bots/api#inputlocationmessagecontent
+func NewInputLocationContent(latitude float64, longitude float64, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"latitude":  latitude,
+		"longitude": longitude,
+	}, extra)
+}
+
+// NewInputVenueContent see https://core.telegram.org/bots/api#inputvenuemessagecontent
+func NewInputVenueContent(latitude float64, longitude float64, title string, address string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"latitude":  latitude,
+		"longitude": longitude,
+		"title":     title,
+		"address":   address,
+	}, extra)
+}
+
+// NewInputContactContent see https://core.telegram.org/bots/api#inputcontactmessagecontent
+func NewInputContactContent(phoneNumber string, firstName string, extra JSONBody) JSONBody {
+	return mergeJSON(JSONBody{
+		"phone_number": phoneNumber,
+		"first_name":   firstName,
+	}, extra)
+}
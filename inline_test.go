@@ -0,0 +1,57 @@
+package easytgbot
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestInlineRoutes(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	bot.Inline(regexp.MustCompile(`^gif `), func(context interface{}, bot *Bot, update Update) JSONBody {
+		return update.AnswerInlineQuery([]JSONBody{NewInlineCachedGif("1", "file", nil)}, nil)
+	})
+	bot.Handle("inline_query", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return update.AnswerInlineQuery([]JSONBody{
+			NewInlineArticle("1", "echo", NewInputTextContent(update.Get("inline_query.query").String(), nil), nil),
+		}, nil)
+	})
+	bot.Handle("chosen_inline_result", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"chosen": update.Get("chosen_inline_result.result_id").String()}
+	})
+
+	res, err := bot.ApplyHandlers(nil, NewUpdate(`{"inline_query":{"id":"q1","from":{"id":7},"query":"gif cat","offset":""}}`))
+	if err != nil || res["inline_query_id"] != "q1" || res["results"].([]JSONBody)[0]["type"] != "gif" {
+		t.Errorf("unexpected answer %v (%v)", res, err)
+	}
+	res, _ = bot.ApplyHandlers(nil, NewUpdate(`{"inline_query":{"id":"q2","from":{"id":7},"query":"hello","offset":""}}`))
+	if res["results"].([]JSONBody)[0]["type"] != "article" {
+		t.Errorf("expected the fallback route, got %v", res)
+	}
+	res, _ = bot.ApplyHandlers(nil, NewUpdate(`{"chosen_inline_result":{"result_id":"r1","from":{"id":7},"query":"hello"}}`))
+	if res["chosen"] != "r1" {
+		t.Errorf("unexpected chosen result %v", res)
+	}
+}
+
+func TestPageInlineResults(t *testing.T) {
+	var results []JSONBody
+	for i := 0; i < 25; i++ {
+		results = append(results, NewInlineArticle(strconv.Itoa(i), "item", NewInputTextContent("item", nil), nil))
+	}
+	offset, pages := "", 0
+	for {
+		page, next := PageInlineResults(results, offset, 10)
+		pages++
+		if next == "" {
+			if len(page) != 5 {
+				t.Errorf("last page has %d results", len(page))
+			}
+			break
+		}
+		offset = next
+	}
+	if pages != 3 {
+		t.Errorf("got %d pages, want 3", pages)
+	}
+}
@@ -0,0 +1,114 @@
+package easytgbot
+
+import (
+	"fmt"
+	"time"
+)
+
+// InviteLinkOptions are the optional fields of an invite link.
+type InviteLinkOptions struct {
+	Name string
+	// ExpireDate is when the link stops working; zero never expires
+	ExpireDate time.Time
+	// MemberLimit is how many users may join with the link, 1-99999;
+	// zero is unlimited
+	MemberLimit int
+	// CreatesJoinRequest makes users who join with the link send a
+	// chat_join_request for approval when true. It can't be used with
+	// MemberLimit. Nil leaves it unset, or unchanged when editing.
+	CreatesJoinRequest *bool
+}
+
+func (opts InviteLinkOptions) body() (JSONBody, error) {
+	joinRequest := opts.CreatesJoinRequest != nil && *opts.CreatesJoinRequest
+	if opts.MemberLimit != 0 && joinRequest {
+		return nil, fmt.Errorf("invite link can't have a member limit and create join requests")
+	}
+	if opts.MemberLimit < 0 || opts.MemberLimit > 99999 {
+		return nil, fmt.Errorf("invite link member limit must be between 1 and 99999")
+	}
+	body := JSONBody{}
+	if opts.CreatesJoinRequest != nil {
+		body["creates_join_request"] = *opts.CreatesJoinRequest
+	}
+	if opts.Name != "" {
+		body["name"] = opts.Name
+	}
+	if !opts.ExpireDate.IsZero() {
+		body["expire_date"] = opts.ExpireDate.Unix()
+	}
+	if opts.MemberLimit > 0 {
+		body["member_limit"] = opts.MemberLimit
+	}
+	return body, nil
+}
+
+// CreateChatInviteLink see https://core.telegram.org/bots/api#createchatinvitelink
+func (bot *Bot) CreateChatInviteLink(chatID int64, opts InviteLinkOptions) (Update, error) {
+	body, err := opts.body()
+	if err != nil {
+		return Update{}, err
+	}
+	return bot.MakeRequest("createChatInviteLink", mergeJSON(body, JSONBody{
+		"chat_id": chatID,
+	}))
+}
+
+// EditChatInviteLink see https://core.telegram.org/bots/api#editchatinvitelink
+func (bot *Bot) EditChatInviteLink(chatID int64, inviteLink string, opts InviteLinkOptions) (Update, error) {
+	body, err := opts.body()
+	if err != nil {
+		return Update{}, err
+	}
+	return bot.MakeRequest("editChatInviteLink", mergeJSON(body, JSONBody{
+		"chat_id":     chatID,
+		"invite_link": inviteLink,
+	}))
+}
+
+// RevokeChatInviteLink see https://core.telegram.org/bots/api#revokechatinvitelink
+func (bot *Bot) RevokeChatInviteLink(chatID int64, inviteLink string) (Update, error) {
+	return bot.MakeRequest("revokeChatInviteLink", JSONBody{
+		"chat_id":     chatID,
+		"invite_link": inviteLink,
+	})
+}
+
+// ApproveChatJoinRequest see https://core.telegram.org/bots/api#approvechatjoinrequest
+func (bot *Bot) ApproveChatJoinRequest(chatID int64, userID int64) (Update, error) {
+	return bot.MakeRequest("approveChatJoinRequest", JSONBody{
+		"chat_id": chatID,
+		"user_id": userID,
+	})
+}
+
+// DeclineChatJoinRequest see https://core.telegram.org/bots/api#declinechatjoinrequest
+func (bot *Bot) DeclineChatJoinRequest(chatID int64, userID int64) (Update, error) {
+	return bot.MakeRequest("declineChatJoinRequest", JSONBody{
+		"chat_id": chatID,
+		"user_id": userID,
+	})
+}
+
+// OnJoinRequest handles chat_join_request updates.
+func (bot *Bot) OnJoinRequest(handler interface{}, filters ...Filter) {
+	bot.Handle("chat_join_request", handler, filters...)
+}
+
+// ApproveChatJoinRequest approves the join request of the update.
+func (update Update) ApproveChatJoinRequest() JSONBody {
+	return JSONBody{
+		"method":  "approveChatJoinRequest",
+		"chat_id": update.ChatID(),
+		"user_id": update.FromID(),
+	}
+}
+
+// DeclineChatJoinRequest declines the join request of the update.
+func (update Update) DeclineChatJoinRequest() JSONBody {
+	return JSONBody{
+		"method":  "declineChatJoinRequest",
+		"chat_id": update.ChatID(),
+		"user_id": update.FromID(),
+	}
+}
@@ -0,0 +1,56 @@
+package easytgbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJoinRequest(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	bot.OnJoinRequest(func(context interface{}, bot *Bot, update Update) JSONBody {
+		if update.Get("chat_join_request.from.is_bot").Bool() {
+			return update.DeclineChatJoinRequest()
+		}
+		return update.ApproveChatJoinRequest()
+	})
+
+	update := NewUpdate(`{"chat_join_request":{"chat":{"id":-100,"type":"supergroup"},"from":{"id":7,"is_bot":false},"user_chat_id":7,"date":0}}`)
+	if _, ok := update.ChatMemberUpdated(); ok {
+		t.Error("join request is not a chat member update")
+	}
+	res, err := bot.ApplyHandlers(nil, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["method"] != "approveChatJoinRequest" || res["chat_id"] != int64(-100) || res["user_id"] != int64(7) {
+		t.Errorf("unexpected answer %v", res)
+	}
+}
+
+func TestCreateChatInviteLink(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	expire := time.Unix(1700000000, 0)
+	if _, err := bot.CreateChatInviteLink(-100, InviteLinkOptions{Name: "promo", ExpireDate: expire, MemberLimit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	call := api.Calls()[0]
+	if call.Params["expire_date"] != float64(expire.Unix()) || call.Params["member_limit"] != float64(10) || call.Params["name"] != "promo" {
+		t.Errorf("unexpected params %v", call.Params)
+	}
+
+	if _, ok := call.Params["creates_join_request"]; ok {
+		t.Errorf("unset creates_join_request was sent: %v", call.Params)
+	}
+
+	joinRequest := true
+	if _, err := bot.CreateChatInviteLink(-100, InviteLinkOptions{MemberLimit: 10, CreatesJoinRequest: &joinRequest}); err == nil {
+		t.Error("expected member limit and join requests to conflict")
+	}
+
+	if _, err := bot.EditChatInviteLink(-100, "https://t.me/+abc", InviteLinkOptions{CreatesJoinRequest: &joinRequest}); err != nil {
+		t.Fatal(err)
+	}
+	if call := api.Calls()[1]; call.Params["creates_join_request"] != true {
+		t.Errorf("unexpected params %v", call.Params)
+	}
+}
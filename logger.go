@@ -0,0 +1,160 @@
+package easytgbot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// Logger is a leveled logger taking a message and key-value pairs,
+// e.g. logger.Warn("api error", "method", "sendMessage", "error_code", 403).
+// *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stdLogger writes "LEVEL msg key=value..." lines to a log.Logger.
+type stdLogger struct {
+	out   *log.Logger
+	debug bool
+}
+
+// NewStdLogger returns a Logger writing to out, or the standard
+// logger when nil. Debug messages are dropped unless debug is set.
+func NewStdLogger(out *log.Logger, debug bool) Logger {
+	if out == nil {
+		out = log.Default()
+	}
+	return stdLogger{out: out, debug: debug}
+}
+
+func (l stdLogger) output(level string, msg string, args []interface{}) {
+	var line strings.Builder
+	line.WriteString(level)
+	line.WriteString(" ")
+	line.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&line, " !BADKEY=%v", args[i])
+			break
+		}
+		value := fmt.Sprint(args[i+1])
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&line, " %v=%s", args[i], value)
+	}
+	l.out.Output(3, line.String())
+}
+
+func (l stdLogger) Debug(msg string, args ...interface{}) {
+	if l.debug {
+		l.output("DEBUG", msg, args)
+	}
+}
+
+func (l stdLogger) Info(msg string, args ...interface{}) {
+	l.output("INFO", msg, args)
+}
+
+func (l stdLogger) Warn(msg string, args ...interface{}) {
+	l.output("WARN", msg, args)
+}
+
+func (l stdLogger) Error(msg string, args ...interface{}) {
+	l.output("ERROR", msg, args)
+}
+
+// defaultLogger logs where no bot is at hand.
+var defaultLogger = NewStdLogger(nil, false)
+
+// redactor hides the bot token.
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+func newRedactor(token string) redactor {
+	pairs := []string{token, "<token>"}
+	if escaped := url.PathEscape(token); escaped != token {
+		pairs = append(pairs, escaped, "<token>")
+	}
+	if escaped := url.QueryEscape(token); escaped != token {
+		pairs = append(pairs, escaped, "<token>")
+	}
+	return redactor{strings.NewReplacer(pairs...)}
+}
+
+func (r redactor) String(s string) string {
+	return r.replacer.Replace(s)
+}
+
+// Error returns err without the token. URL errors keep their type.
+func (r redactor) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		inner := urlErr.Err
+		if msg := inner.Error(); r.String(msg) != msg {
+			inner = errors.New(r.String(msg))
+		}
+		return &url.Error{Op: urlErr.Op, URL: r.String(urlErr.URL), Err: inner}
+	}
+	if msg := err.Error(); r.String(msg) != msg {
+		return errors.New(r.String(msg))
+	}
+	return err
+}
+
+// redactLogger removes the token from messages and values.
+type redactLogger struct {
+	next     Logger
+	redactor redactor
+}
+
+func (l redactLogger) args(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			redacted[i] = l.redactor.String(v)
+		case error:
+			redacted[i] = l.redactor.Error(v)
+		case fmt.Stringer:
+			redacted[i] = l.redactor.String(v.String())
+		case []byte:
+			redacted[i] = l.redactor.String(string(v))
+		default:
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+func (l redactLogger) Debug(msg string, args ...interface{}) {
+	l.next.Debug(l.redactor.String(msg), l.args(args)...)
+}
+
+func (l redactLogger) Info(msg string, args ...interface{}) {
+	l.next.Info(l.redactor.String(msg), l.args(args)...)
+}
+
+func (l redactLogger) Warn(msg string, args ...interface{}) {
+	l.next.Warn(l.redactor.String(msg), l.args(args)...)
+}
+
+func (l redactLogger) Error(msg string, args ...interface{}) {
+	l.next.Error(l.redactor.String(msg), l.args(args)...)
+}
+
+// Logger returns the logger of the bot; the token is redacted from
+// everything it logs.
+func (bot *Bot) Logger() Logger {
+	return bot.logger
+}
@@ -0,0 +1,68 @@
+package easytgbot
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordLogger keeps log entries as text.
+type recordLogger struct {
+	mutex   sync.Mutex
+	entries []string
+}
+
+func (l *recordLogger) record(level string, msg string, args []interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (l *recordLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *recordLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *recordLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *recordLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+func TestLoggerRedactsToken(t *testing.T) {
+	logger := &recordLogger{}
+	bot, api := newTestBot(t, Settings{Logger: logger})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		return nil, &Error{Code: 401, Message: "Unauthorized: 123:token is invalid"}
+	}
+	_, err := bot.SendMessage(7, "hi", nil)
+	if err == nil || strings.Contains(err.Error(), "123:token") {
+		t.Errorf("token in error %v", err)
+	}
+
+	bot.apiEndpoint = "http://127.0.0.1:1/bot%s/%s"
+	_, err = bot.GetMe()
+	if err == nil || strings.Contains(err.Error(), "123:token") || !strings.Contains(err.Error(), "<token>") {
+		t.Errorf("token in error %v", err)
+	}
+	bot.Logger().Info("token is 123:token", "url", "https://host/bot123:token/getMe")
+
+	if len(logger.entries) != 3 {
+		t.Fatalf("got entries %q", logger.entries)
+	}
+	for _, entry := range logger.entries {
+		if strings.Contains(entry, "123:token") {
+			t.Errorf("token in log %s", entry)
+		}
+	}
+	if !strings.Contains(logger.entries[0], "WARN api error [method sendMessage chat_id 7 latency") || !strings.Contains(logger.entries[0], "error_code 401") {
+		t.Errorf("unexpected entry %s", logger.entries[0])
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewStdLogger(log.New(&out, "", 0), false)
+	logger.Debug("hidden")
+	logger.Warn("api error", "method", "sendMessage", "error", "Bad Request: chat not found", "odd")
+	if got := out.String(); got != "WARN api error method=sendMessage error=\"Bad Request: chat not found\" !BADKEY=odd\n" {
+		t.Errorf("got %q", got)
+	}
+}
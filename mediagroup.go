@@ -0,0 +1,128 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mediaGroup is an album being collected.
+type mediaGroup struct {
+	node    string
+	updates []Update
+	timer   *time.Timer
+}
+
+// MediaGroup returns the messages of an album update, or nil.
+func (update Update) MediaGroup() []Update {
+	group := update.Get("media_group.messages")
+	if !group.Exists() {
+		return nil
+	}
+	return group.Array()
+}
+
+// bufferMediaGroup holds back album messages until no message of the
+// album arrived for MediaGroupWait, then dispatches one album update.
+// It reports whether update was buffered.
+func (bot *Bot) bufferMediaGroup(update Update) bool {
+	if bot.MediaGroupWait <= 0 || update.Get("media_group").Exists() {
+		return false
+	}
+	var node string
+	var message Update
+	for _, t := range MessageNodes {
+		if message = update.Get(t); message.Exists() {
+			node = t
+			break
+		}
+	}
+	groupID := message.Get("media_group_id").String()
+	if node == "" || groupID == "" {
+		return false
+	}
+	key := fmt.Sprintf("%d:%s", message.Get("chat.id").Int(), groupID)
+
+	bot.mediaGroupMutex.Lock()
+	defer bot.mediaGroupMutex.Unlock()
+	group, ok := bot.mediaGroups[key]
+	if !ok {
+		group = &mediaGroup{node: node}
+		bot.mediaGroups[key] = group
+		group.timer = time.AfterFunc(bot.MediaGroupWait, func() {
+			bot.flushMediaGroup(key)
+		})
+	} else {
+		group.timer.Reset(bot.MediaGroupWait)
+	}
+	group.updates = append(group.updates, update)
+	return true
+}
+
+// flushMediaGroup dispatches a collected album with a nil context.
+// Panics are reported as a PanicError.
+func (bot *Bot) flushMediaGroup(key string) {
+	bot.mediaGroupMutex.Lock()
+	group, ok := bot.mediaGroups[key]
+	delete(bot.mediaGroups, key)
+	bot.mediaGroupMutex.Unlock()
+	if !ok {
+		return
+	}
+	defer func() {
+		if value := recover(); value != nil {
+			bot.reportError(&PanicError{Value: value, Stack: debug.Stack()}, group.updates[0])
+		}
+	}()
+
+	album, err := newAlbum(group)
+	if err != nil {
+		bot.reportError(err, group.updates[0])
+		return
+	}
+	res, err := bot.ApplyHandlers(nil, album)
+	if err != nil {
+		return
+	}
+	if err := bot.Respond(nil, res); err != nil {
+		bot.reportError(err, album)
+	}
+}
+
+// stopMediaGroups drops the albums being collected.
+func (bot *Bot) stopMediaGroups() {
+	bot.mediaGroupMutex.Lock()
+	defer bot.mediaGroupMutex.Unlock()
+	for key, group := range bot.mediaGroups {
+		group.timer.Stop()
+		delete(bot.mediaGroups, key)
+	}
+}
+
+// newAlbum builds the album update: the first message under its usual
+// node, so Chat and From keep working, and all messages in order
+// under media_group.messages.
+func newAlbum(group *mediaGroup) (Update, error) {
+	sort.Slice(group.updates, func(i, j int) bool {
+		return group.updates[i].Get(group.node+".message_id").Int() < group.updates[j].Get(group.node+".message_id").Int()
+	})
+	messages := make([]string, 0, len(group.updates))
+	for _, update := range group.updates {
+		messages = append(messages, update.Get(group.node).Raw)
+	}
+	first := group.updates[0].Get(group.node)
+	node, _ := json.Marshal(group.node)
+	raw := fmt.Sprintf(`{"update_id":%d,%s:%s,"media_group":{"id":%s,"messages":[%s]}}`,
+		group.updates[0].Get("update_id").Int(),
+		node, first.Raw,
+		first.Get("media_group_id").Raw,
+		strings.Join(messages, ","),
+	)
+	if !json.Valid([]byte(raw)) {
+		return Update{}, fmt.Errorf("invalid album")
+	}
+	return NewUpdate(raw), nil
+}
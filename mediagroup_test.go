@@ -0,0 +1,73 @@
+package easytgbot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMediaGroup(t *testing.T) {
+	bot, api := newTestBot(t, Settings{MediaGroupWait: 20 * time.Millisecond})
+	var albumContext interface{}
+	bot.Handle("media_group", func(context interface{}, bot *Bot, update Update) JSONBody {
+		albumContext = context
+		captions := ""
+		for _, message := range update.MediaGroup() {
+			captions += message.Get("caption").String()
+		}
+		return update.SendMessage(fmt.Sprintf("%d:%s", len(update.MediaGroup()), captions), nil)
+	})
+	photos := 0
+	bot.Handle("photo", func(context interface{}, bot *Bot, update Update) JSONBody {
+		photos++
+		return JSONBody{}
+	})
+
+	for _, id := range []int{12, 11, 13} {
+		update := NewUpdate(fmt.Sprintf(`{"update_id":%d,"message":{"message_id":%d,"chat":{"id":7,"type":"private"},"from":{"id":7},"media_group_id":"g1","photo":[{"file_id":"f%d"}],"caption":"c%d"}}`, id, id, id, id))
+		if res, err := bot.ApplyHandlers("request", update); err != nil || len(res) != 0 {
+			t.Fatalf("album message was not buffered: %v (%v)", res, err)
+		}
+	}
+	bot.ApplyHandlers(nil, NewUpdate(`{"update_id":20,"message":{"message_id":20,"chat":{"id":7,"type":"private"},"photo":[{"file_id":"single"}]}}`))
+
+	time.Sleep(100 * time.Millisecond)
+	calls := api.Calls()
+	if len(calls) != 1 || calls[0].Method != "sendMessage" || calls[0].Params["text"] != "3:c11c12c13" {
+		t.Errorf("unexpected calls %+v", calls)
+	}
+	if photos != 1 {
+		t.Errorf("photo handler ran %d times, want 1", photos)
+	}
+	if albumContext != nil {
+		t.Errorf("album handler got context %v, want nil", albumContext)
+	}
+
+	// albums still collecting are dropped on stop
+	bot.ApplyHandlers(nil, NewUpdate(`{"update_id":30,"message":{"message_id":30,"chat":{"id":7,"type":"private"},"media_group_id":"g2","photo":[{"file_id":"f30"}]}}`))
+	bot.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if calls := api.Calls(); len(calls) != 1 {
+		t.Errorf("album was handled after stop: %+v", calls)
+	}
+}
+
+func TestMediaGroupPanic(t *testing.T) {
+	bot, _ := newTestBot(t, Settings{MediaGroupWait: 10 * time.Millisecond})
+	bot.Handle("media_group", func(context interface{}, bot *Bot, update Update) JSONBody {
+		panic("broken album")
+	})
+	reported := make(chan error, 1)
+	bot.OnError(func(err error, update Update) {
+		reported <- err
+	})
+	bot.ApplyHandlers(nil, NewUpdate(`{"update_id":1,"message":{"message_id":1,"chat":{"id":7,"type":"private"},"media_group_id":"g1","photo":[{"file_id":"f1"}]}}`))
+	select {
+	case err := <-reported:
+		if panicErr, ok := err.(*PanicError); !ok || panicErr.Value != "broken album" {
+			t.Errorf("reported %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("the album panic was not reported")
+	}
+}
@@ -0,0 +1,352 @@
+package easytgbot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// menuData is the callback data of menu buttons.
+// The arg field holds the page shown, or the list entry chosen.
+var menuData = NewCallbackData("menu", "id", "action", "item", "arg")
+
+// menu actions
+const (
+	menuButton  = "b"
+	menuSubmenu = "s"
+	menuToggle  = "t"
+	menuSelect  = "l"
+	menuPage    = "p"
+	menuBack    = "back"
+)
+
+// menuStackTTL is how long the back stack of an idle menu is kept.
+const menuStackTTL = 48 * time.Hour
+
+// menuStack is the back stack of a menu message, entries "id:page".
+type menuStack struct {
+	entries []string
+	used    time.Time
+}
+
+// MenuSelectFunc handles the choice of the item at index in a menu list.
+type MenuSelectFunc func(context interface{}, bot *Bot, update Update, index int) JSONBody
+
+// Menu is an inline keyboard menu edited in place while navigating.
+// Buttons added in a row sit side by side; Row starts a new row.
+// Submenus get a back button returning to the menu they were opened
+// from, remembered for 48 hours of inactivity. Navigation answers the
+// callback query along with the edit.
+type Menu struct {
+	ID   string
+	Text string
+	// TextFunc overrides Text, e.g. to show current settings
+	TextFunc func(Update) string
+
+	// BackLabel, PrevLabel and NextLabel label the navigation buttons.
+	BackLabel string // Default: "« Back"
+	PrevLabel string // Default: "‹ Prev"
+	NextLabel string // Default: "Next ›"
+
+	items []*menuItem
+	rows  [][]int
+}
+
+type menuItem struct {
+	action  string
+	label   string
+	handler HandlerFunc
+	submenu *Menu
+	get     func(Update) bool
+	set     func(Update, bool)
+	list    func(Update) []string
+	size    int
+	choose  MenuSelectFunc
+}
+
+// NewMenu creates a menu; id must be unique per bot.
+func NewMenu(id string, text string) *Menu {
+	return &Menu{
+		ID:        id,
+		Text:      text,
+		BackLabel: "« Back",
+		PrevLabel: "‹ Prev",
+		NextLabel: "Next ›",
+		rows:      [][]int{{}},
+	}
+}
+
+// Row starts a new row of buttons.
+func (m *Menu) Row() *Menu {
+	if len(m.rows[len(m.rows)-1]) > 0 {
+		m.rows = append(m.rows, []int{})
+	}
+	return m
+}
+
+func (m *Menu) add(item *menuItem) *Menu {
+	m.items = append(m.items, item)
+	m.rows[len(m.rows)-1] = append(m.rows[len(m.rows)-1], len(m.items)-1)
+	return m
+}
+
+// Button adds a button running handler.
+func (m *Menu) Button(label string, handler HandlerFunc) *Menu {
+	return m.add(&menuItem{action: menuButton, label: label, handler: handler})
+}
+
+// Submenu adds a button opening sub in place.
+func (m *Menu) Submenu(label string, sub *Menu) *Menu {
+	return m.add(&menuItem{action: menuSubmenu, label: label, submenu: sub})
+}
+
+// Toggle adds an on/off button showing the value of get and
+// flipping it with set.
+func (m *Menu) Toggle(label string, get func(Update) bool, set func(Update, bool)) *Menu {
+	return m.add(&menuItem{action: menuToggle, label: label, get: get, set: set})
+}
+
+// List adds a paginated list of items, one per row, with previous and
+// next buttons. A menu has at most one list.
+func (m *Menu) List(items func(Update) []string, pageSize int, choose MenuSelectFunc) *Menu {
+	if pageSize <= 0 {
+		pageSize = 5
+	}
+	m.Row()
+	m.add(&menuItem{action: menuSelect, list: items, size: pageSize, choose: choose})
+	return m.Row()
+}
+
+// RegisterMenu registers a menu and its submenus. Like handlers, menus
+// are registered at setup, before updates are handled.
+func (bot *Bot) RegisterMenu(m *Menu) {
+	bot.menuMutex.Lock()
+	registered := bot.registerMenu(m)
+	bot.menuMutex.Unlock()
+	if registered {
+		bot.Action(menuData.Pattern(), bot.menuAction)
+	}
+}
+
+func (bot *Bot) registerMenu(m *Menu) bool {
+	if bot.menus[m.ID] == m {
+		return false
+	}
+	bot.menus[m.ID] = m
+	for _, item := range m.items {
+		if item.submenu != nil {
+			bot.registerMenu(item.submenu)
+		}
+	}
+	return true
+}
+
+// ShowMenu sends the menu as a new message. It fails the update when
+// the menu is not registered.
+func (bot *Bot) ShowMenu(update Update, m *Menu) JSONBody {
+	bot.menuMutex.Lock()
+	registered := bot.menus[m.ID] == m
+	bot.menuMutex.Unlock()
+	if !registered {
+		return Fail(fmt.Errorf("menu %s is not registered", m.ID))
+	}
+	text, keyboard, err := bot.renderMenu(m, update, 0, false)
+	if err != nil {
+		return JSONBody{}
+	}
+	return update.SendMessage(text, JSONBody{"reply_markup": keyboard})
+}
+
+// renderMenu returns the text and keyboard of a menu page.
+func (bot *Bot) renderMenu(m *Menu, update Update, page int, back bool) (string, JSONBody, error) {
+	button := func(label string, action string, item int, arg int) (JSONBody, error) {
+		data, err := bot.PackCallback(menuData, m.ID, action, item, arg)
+		return JSONBody{"text": label, "callback_data": data}, err
+	}
+
+	keyboard := [][]JSONBody{}
+	for _, row := range m.rows {
+		buttons := []JSONBody{}
+		for _, index := range row {
+			item := m.items[index]
+			switch item.action {
+			case menuSelect:
+				entries := item.list(update)
+				start, end := page*item.size, (page+1)*item.size
+				if end > len(entries) {
+					end = len(entries)
+				}
+				for i := start; i < end; i++ {
+					b, err := button(entries[i], menuSelect, index, i)
+					if err != nil {
+						return "", nil, err
+					}
+					keyboard = append(keyboard, []JSONBody{b})
+				}
+				nav := []JSONBody{}
+				if page > 0 {
+					b, err := button(m.PrevLabel, menuPage, index, page-1)
+					if err != nil {
+						return "", nil, err
+					}
+					nav = append(nav, b)
+				}
+				if end < len(entries) {
+					b, err := button(m.NextLabel, menuPage, index, page+1)
+					if err != nil {
+						return "", nil, err
+					}
+					nav = append(nav, b)
+				}
+				if len(nav) > 0 {
+					keyboard = append(keyboard, nav)
+				}
+				continue
+			case menuToggle:
+				label := "⬜ " + item.label
+				if item.get(update) {
+					label = "✅ " + item.label
+				}
+				b, err := button(label, item.action, index, page)
+				if err != nil {
+					return "", nil, err
+				}
+				buttons = append(buttons, b)
+			default:
+				b, err := button(item.label, item.action, index, page)
+				if err != nil {
+					return "", nil, err
+				}
+				buttons = append(buttons, b)
+			}
+		}
+		if len(buttons) > 0 {
+			keyboard = append(keyboard, buttons)
+		}
+	}
+	if back {
+		b, err := button(m.BackLabel, menuBack, 0, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		keyboard = append(keyboard, []JSONBody{b})
+	}
+
+	text := m.Text
+	if m.TextFunc != nil {
+		text = m.TextFunc(update)
+	}
+	return text, JSONBody{"inline_keyboard": keyboard}, nil
+}
+
+// editMenu shows a menu page in place of the message of update.
+func (bot *Bot) editMenu(m *Menu, update Update, page int, key string) JSONBody {
+	bot.menuMutex.Lock()
+	stack, ok := bot.menuStacks[key]
+	back := ok && len(stack.entries) > 0
+	bot.menuMutex.Unlock()
+	text, keyboard, err := bot.renderMenu(m, update, page, back)
+	if err != nil {
+		return update.AnswerCallbackQuery(err.Error(), nil)
+	}
+	return Batch(
+		update.AnswerCallbackQuery("", JSONBody{"show_alert": false}),
+		update.EditMessageText(text, JSONBody{"reply_markup": keyboard}),
+	)
+}
+
+// pushMenu adds entry to the back stack of key, dropping idle stacks.
+func (bot *Bot) pushMenu(key string, entry string) {
+	now := time.Now()
+	bot.menuMutex.Lock()
+	defer bot.menuMutex.Unlock()
+	if now.Sub(bot.menuSwept) > menuStackTTL {
+		for k, stack := range bot.menuStacks {
+			if now.Sub(stack.used) > menuStackTTL {
+				delete(bot.menuStacks, k)
+			}
+		}
+		bot.menuSwept = now
+	}
+	stack, ok := bot.menuStacks[key]
+	if !ok {
+		stack = &menuStack{}
+		bot.menuStacks[key] = stack
+	}
+	stack.entries = append(stack.entries, entry)
+	stack.used = now
+}
+
+// popMenu removes the last entry of the back stack of key.
+func (bot *Bot) popMenu(key string) (string, bool) {
+	bot.menuMutex.Lock()
+	defer bot.menuMutex.Unlock()
+	stack, ok := bot.menuStacks[key]
+	if !ok || len(stack.entries) == 0 || time.Since(stack.used) > menuStackTTL {
+		delete(bot.menuStacks, key)
+		return "", false
+	}
+	entry := stack.entries[len(stack.entries)-1]
+	stack.entries = stack.entries[:len(stack.entries)-1]
+	stack.used = time.Now()
+	if len(stack.entries) == 0 {
+		delete(bot.menuStacks, key)
+	}
+	return entry, true
+}
+
+// menuAction handles the buttons of all menus.
+func (bot *Bot) menuAction(context interface{}, _ *Bot, update Update) JSONBody {
+	values, err := menuData.Parse(update)
+	if err != nil {
+		return JSONBody{}
+	}
+	bot.menuMutex.Lock()
+	m, ok := bot.menus[values.String("id")]
+	bot.menuMutex.Unlock()
+	index, arg := int(values.Int("item")), int(values.Int("arg"))
+	action := values.String("action")
+	if !ok || (action != menuBack && (index < 0 || index >= len(m.items))) {
+		return update.AnswerCallbackQuery("This menu is no longer available.", nil)
+	}
+	message, _ := update.Message()
+	key := fmt.Sprintf("%d:%d", update.ChatID(), message.Get("message_id").Int())
+	var item *menuItem
+	if action != menuBack {
+		item = m.items[index]
+	}
+
+	switch action {
+	case menuButton:
+		return item.handler(context, bot, update)
+	case menuSubmenu:
+		bot.pushMenu(key, m.ID+":"+strconv.Itoa(arg))
+		return bot.editMenu(item.submenu, update, 0, key)
+	case menuToggle:
+		item.set(update, !item.get(update))
+		return bot.editMenu(m, update, arg, key)
+	case menuSelect:
+		return item.choose(context, bot, update, arg)
+	case menuPage:
+		return bot.editMenu(m, update, arg, key)
+	case menuBack:
+		parent, ok := bot.popMenu(key)
+		if !ok {
+			return bot.editMenu(m, update, 0, key)
+		}
+		id, page := parent, 0
+		if i := strings.LastIndex(parent, ":"); i >= 0 {
+			id = parent[:i]
+			page, _ = strconv.Atoi(parent[i+1:])
+		}
+		bot.menuMutex.Lock()
+		m, ok = bot.menus[id]
+		bot.menuMutex.Unlock()
+		if !ok {
+			return update.AnswerCallbackQuery("This menu is no longer available.", nil)
+		}
+		return bot.editMenu(m, update, page, key)
+	}
+	return JSONBody{}
+}
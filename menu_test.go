@@ -0,0 +1,98 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// menuPress presses the button labelled text on the keyboard of res
+// and returns the last action of the result.
+func menuPress(t *testing.T, bot *Bot, res JSONBody, text string) JSONBody {
+	t.Helper()
+	raw, _ := json.Marshal(res["reply_markup"])
+	for _, row := range NewUpdate(string(raw)).Get("inline_keyboard").Array() {
+		for _, button := range row.Array() {
+			if button.Get("text").String() != text {
+				continue
+			}
+			update := NewUpdate(fmt.Sprintf(`{"callback_query":{"id":"1","from":{"id":7},"data":%q,"message":{"message_id":5,"chat":{"id":7,"type":"private"}}}}`, button.Get("callback_data").String()))
+			res, err := bot.ApplyHandlers(nil, update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// navigation answers the query along with the edit
+			actions := Actions(res)
+			if len(actions) > 1 && actions[0]["method"] != "answerCallbackQuery" {
+				t.Errorf("unanswered callback query: %v", actions)
+			}
+			return actions[len(actions)-1]
+		}
+	}
+	t.Fatalf("no %q button in %s", text, raw)
+	return nil
+}
+
+func TestMenu(t *testing.T) {
+	bot, _ := New("token", Settings{CallbackSecret: "secret"})
+
+	notify := false
+	items := []string{"a", "b", "c", "d", "e"}
+	var chosen int
+	sub := NewMenu("settings", "Settings").
+		Toggle("Notify", func(Update) bool { return notify }, func(_ Update, v bool) { notify = v }).
+		List(func(Update) []string { return items }, 2, func(context interface{}, bot *Bot, update Update, index int) JSONBody {
+			chosen = index
+			return update.AnswerCallbackQuery(items[index], nil)
+		})
+	root := NewMenu("main", "Main").
+		Button("Hi", func(context interface{}, bot *Bot, update Update) JSONBody {
+			return update.AnswerCallbackQuery("hi", nil)
+		}).
+		Submenu("Settings", sub)
+
+	command := NewUpdate(`{"message":{"message_id":1,"chat":{"id":7,"type":"private"},"text":"/menu"}}`)
+	if res := bot.ShowMenu(command, root); ResultError(res) == nil {
+		t.Errorf("expected an unregistered menu to fail, got %v", res)
+	}
+	bot.RegisterMenu(root)
+	res := bot.ShowMenu(command, root)
+	if res["method"] != "sendMessage" || res["text"] != "Main" {
+		t.Fatalf("unexpected menu %v", res)
+	}
+	if got := menuPress(t, bot, res, "Hi"); got["text"] != "hi" {
+		t.Errorf("button got %v", got)
+	}
+
+	settings := menuPress(t, bot, res, "Settings")
+	if settings["method"] != "editMessageText" || settings["text"] != "Settings" {
+		t.Fatalf("submenu got %v", settings)
+	}
+	settings = menuPress(t, bot, settings, "⬜ Notify")
+	if !notify {
+		t.Error("toggle did not flip the value")
+	}
+	settings = menuPress(t, bot, settings, "Next ›")
+	settings = menuPress(t, bot, settings, "Next ›")
+	if got := menuPress(t, bot, settings, "e"); got["text"] != "e" || chosen != 4 {
+		t.Errorf("select got %v (%d)", got, chosen)
+	}
+	settings = menuPress(t, bot, settings, "‹ Prev")
+	menuPress(t, bot, settings, "d")
+
+	main := menuPress(t, bot, settings, "« Back")
+	if main["text"] != "Main" {
+		t.Errorf("back got %v", main)
+	}
+	if len(bot.menuStacks) != 0 {
+		t.Errorf("back stack was not emptied: %v", bot.menuStacks)
+	}
+
+	// idle back stacks expire
+	settings = menuPress(t, bot, main, "Settings")
+	bot.menuStacks["7:5"].used = time.Now().Add(-menuStackTTL - time.Minute)
+	if main = menuPress(t, bot, settings, "« Back"); main["text"] != "Settings" || len(bot.menuStacks) != 0 {
+		t.Errorf("expired back stack was used: %v", main)
+	}
+}
@@ -0,0 +1,243 @@
+package easytgbot
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds in seconds of latency histograms.
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Latency is a latency histogram; Buckets counts observations up to
+// each of LatencyBuckets, cumulatively.
+type Latency struct {
+	Count   uint64   `json:"count"`
+	Sum     float64  `json:"sum"`
+	Buckets []uint64 `json:"buckets"`
+}
+
+func (l *Latency) observe(d time.Duration) {
+	if l.Buckets == nil {
+		l.Buckets = make([]uint64, len(LatencyBuckets))
+	}
+	seconds := d.Seconds()
+	l.Count++
+	l.Sum += seconds
+	for i, bound := range LatencyBuckets {
+		if seconds <= bound {
+			l.Buckets[i]++
+		}
+	}
+}
+
+func (l *Latency) copy() Latency {
+	return Latency{Count: l.Count, Sum: l.Sum, Buckets: append([]uint64{}, l.Buckets...)}
+}
+
+// MethodStats are the metrics of a Bot API method. Calls are counted
+// by error code: "200" for success, "error" when no response came.
+type MethodStats struct {
+	Calls   map[string]uint64 `json:"calls"`
+	Latency Latency           `json:"latency"`
+}
+
+// RouteStats are the metrics of a route.
+type RouteStats struct {
+	Count   uint64  `json:"count"`
+	Errors  uint64  `json:"errors"`
+	Latency Latency `json:"latency"`
+}
+
+// MetricsSnapshot is a copy of the metrics of a bot.
+type MetricsSnapshot struct {
+	API           map[string]MethodStats `json:"api"`
+	Routes        map[string]RouteStats  `json:"routes"`
+	QueueDepth    int                    `json:"queue_depth"`
+	QueueCapacity int                    `json:"queue_capacity"`
+}
+
+// Metrics counts API calls and handled updates of a bot.
+// Routes are named by their endpoint, "action:" or "inline:" and
+// their pattern, "scene", "panic" for panics outside Recover,
+// "middleware" for updates answered by UseAll middleware, or
+// "unhandled".
+type Metrics struct {
+	api    map[string]*MethodStats
+	routes map[string]*RouteStats
+	queue  chan Update
+	mutex  sync.Mutex
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		api:    make(map[string]*MethodStats),
+		routes: make(map[string]*RouteStats),
+	}
+}
+
+// Metrics returns the metrics of the bot.
+func (bot *Bot) Metrics() *Metrics {
+	return bot.metrics
+}
+
+func (m *Metrics) observeRequest(method string, code string, d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats, ok := m.api[method]
+	if !ok {
+		stats = &MethodStats{Calls: map[string]uint64{}}
+		m.api[method] = stats
+	}
+	stats.Calls[code]++
+	stats.Latency.observe(d)
+}
+
+func (m *Metrics) observeUpdate(route string, err error, d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats, ok := m.routes[route]
+	if !ok {
+		stats = &RouteStats{}
+		m.routes[route] = stats
+	}
+	stats.Count++
+	if err != nil && err != ErrUnhandled {
+		stats.Errors++
+	}
+	stats.Latency.observe(d)
+}
+
+func (m *Metrics) setQueue(queue chan Update) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.queue = queue
+}
+
+// Snapshot returns a copy of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	snapshot := MetricsSnapshot{
+		API:    make(map[string]MethodStats, len(m.api)),
+		Routes: make(map[string]RouteStats, len(m.routes)),
+	}
+	for method, stats := range m.api {
+		calls := make(map[string]uint64, len(stats.Calls))
+		for code, count := range stats.Calls {
+			calls[code] = count
+		}
+		snapshot.API[method] = MethodStats{Calls: calls, Latency: stats.Latency.copy()}
+	}
+	for route, stats := range m.routes {
+		snapshot.Routes[route] = RouteStats{Count: stats.Count, Errors: stats.Errors, Latency: stats.Latency.copy()}
+	}
+	if m.queue != nil {
+		snapshot.QueueDepth, snapshot.QueueCapacity = len(m.queue), cap(m.queue)
+	}
+	return snapshot
+}
+
+// PublishExpvar publishes the metrics as an expvar variable, e.g. on
+// /debug/vars. Like expvar.Publish, it panics if name is taken.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	var out strings.Builder
+
+	methods := make([]string, 0, len(snapshot.API))
+	for method := range snapshot.API {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	routes := make([]string, 0, len(snapshot.Routes))
+	for route := range snapshot.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	out.WriteString("# HELP easytgbot_api_requests_total Bot API requests by method and error code.\n")
+	out.WriteString("# TYPE easytgbot_api_requests_total counter\n")
+	for _, method := range methods {
+		calls := snapshot.API[method].Calls
+		codes := make([]string, 0, len(calls))
+		for code := range calls {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(&out, "easytgbot_api_requests_total{method=%s,code=%s} %d\n", promLabel(method), promLabel(code), calls[code])
+		}
+	}
+	out.WriteString("# HELP easytgbot_api_request_duration_seconds Bot API request latency.\n")
+	out.WriteString("# TYPE easytgbot_api_request_duration_seconds histogram\n")
+	for _, method := range methods {
+		writeHistogram(&out, "easytgbot_api_request_duration_seconds", "method="+promLabel(method), snapshot.API[method].Latency)
+	}
+
+	out.WriteString("# HELP easytgbot_updates_total Handled updates by route.\n")
+	out.WriteString("# TYPE easytgbot_updates_total counter\n")
+	for _, route := range routes {
+		fmt.Fprintf(&out, "easytgbot_updates_total{route=%s} %d\n", promLabel(route), snapshot.Routes[route].Count)
+	}
+	out.WriteString("# HELP easytgbot_update_errors_total Failed updates by route.\n")
+	out.WriteString("# TYPE easytgbot_update_errors_total counter\n")
+	for _, route := range routes {
+		fmt.Fprintf(&out, "easytgbot_update_errors_total{route=%s} %d\n", promLabel(route), snapshot.Routes[route].Errors)
+	}
+	out.WriteString("# HELP easytgbot_update_duration_seconds Update handling latency by route.\n")
+	out.WriteString("# TYPE easytgbot_update_duration_seconds histogram\n")
+	for _, route := range routes {
+		writeHistogram(&out, "easytgbot_update_duration_seconds", "route="+promLabel(route), snapshot.Routes[route].Latency)
+	}
+
+	out.WriteString("# HELP easytgbot_update_queue_depth Updates waiting in the polling channel.\n")
+	out.WriteString("# TYPE easytgbot_update_queue_depth gauge\n")
+	fmt.Fprintf(&out, "easytgbot_update_queue_depth %d\n", snapshot.QueueDepth)
+	out.WriteString("# HELP easytgbot_update_queue_capacity Capacity of the polling channel.\n")
+	out.WriteString("# TYPE easytgbot_update_queue_capacity gauge\n")
+	fmt.Fprintf(&out, "easytgbot_update_queue_capacity %d\n", snapshot.QueueCapacity)
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+func writeHistogram(out *strings.Builder, name string, labels string, latency Latency) {
+	for i, bound := range LatencyBuckets {
+		count := uint64(0)
+		if i < len(latency.Buckets) {
+			count = latency.Buckets[i]
+		}
+		fmt.Fprintf(out, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), count)
+	}
+	fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, latency.Count)
+	fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(latency.Sum, 'g', -1, 64))
+	fmt.Fprintf(out, "%s_count{%s} %d\n", name, labels, latency.Count)
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabel quotes a label value.
+func promLabel(value string) string {
+	return `"` + promEscaper.Replace(value) + `"`
+}
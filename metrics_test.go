@@ -0,0 +1,74 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		if params["chat_id"] == float64(2) {
+			return nil, &Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+		}
+		return true, nil
+	}
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) (JSONBody, error) {
+		return nil, errors.New("failed")
+	})
+	bot.Action(regexp.MustCompile(`^vote:`), func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{}
+	})
+	bot.OnError(func(error, Update) {})
+
+	bot.SendMessage(1, "hi", nil)
+	bot.SendMessage(2, "hi", nil)
+	bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"chat":{"id":1,"type":"private"},"text":"hi"}}`))
+	bot.ApplyHandlers(nil, NewUpdate(`{"callback_query":{"id":"1","from":{"id":1},"data":"vote:1"}}`))
+	bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"chat":{"id":1,"type":"private"},"sticker":{}}}`))
+
+	snapshot := bot.Metrics().Snapshot()
+	send := snapshot.API["sendMessage"]
+	if send.Calls["200"] != 1 || send.Calls["403"] != 1 || send.Latency.Count != 2 {
+		t.Errorf("unexpected api metrics %+v", send)
+	}
+	if r := snapshot.Routes["text"]; r.Count != 1 || r.Errors != 1 {
+		t.Errorf("unexpected text route %+v", r)
+	}
+	if r := snapshot.Routes["action:^vote:"]; r.Count != 1 || r.Errors != 0 {
+		t.Errorf("unexpected action route %+v", snapshot.Routes)
+	}
+	if r := snapshot.Routes["unhandled"]; r.Count != 1 || r.Errors != 0 {
+		t.Errorf("unexpected unhandled route %+v", snapshot.Routes)
+	}
+
+	w := httptest.NewRecorder()
+	bot.Metrics().Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`easytgbot_api_requests_total{method="sendMessage",code="403"} 1`,
+		`easytgbot_api_request_duration_seconds_count{method="sendMessage"} 2`,
+		`easytgbot_api_request_duration_seconds_bucket{method="sendMessage",le="+Inf"} 2`,
+		`easytgbot_update_errors_total{route="text"} 1`,
+		`easytgbot_updates_total{route="action:^vote:"} 1`,
+		`easytgbot_update_queue_depth 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %s in\n%s", line, body)
+		}
+	}
+
+	bot.Metrics().PublishExpvar("easytgbot_test")
+	var published MetricsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get("easytgbot_test").String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Routes["text"].Errors != 1 {
+		t.Errorf("unexpected expvar %+v", published)
+	}
+}
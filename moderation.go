@@ -0,0 +1,236 @@
+package easytgbot
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Restrictions and bans shorter than MinRestriction or longer than
+// MaxRestriction are treated as forever by Telegram; see UntilDate.
+const (
+	MinRestriction = 30 * time.Second
+	MaxRestriction = 366 * 24 * time.Hour
+)
+
+// ChatPermissions see https://core.telegram.org/bots/api#chatpermissions
+type ChatPermissions struct {
+	CanSendMessages       bool `json:"can_send_messages"`
+	CanSendAudios         bool `json:"can_send_audios"`
+	CanSendDocuments      bool `json:"can_send_documents"`
+	CanSendPhotos         bool `json:"can_send_photos"`
+	CanSendVideos         bool `json:"can_send_videos"`
+	CanSendVideoNotes     bool `json:"can_send_video_notes"`
+	CanSendVoiceNotes     bool `json:"can_send_voice_notes"`
+	CanSendPolls          bool `json:"can_send_polls"`
+	CanSendOtherMessages  bool `json:"can_send_other_messages"`
+	CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
+	CanChangeInfo         bool `json:"can_change_info"`
+	CanInviteUsers        bool `json:"can_invite_users"`
+	CanPinMessages        bool `json:"can_pin_messages"`
+	CanManageTopics       bool `json:"can_manage_topics"`
+}
+
+// AllPermissions allows everything a member can do, used to unmute.
+func AllPermissions() ChatPermissions {
+	return ChatPermissions{
+		CanSendMessages:       true,
+		CanSendAudios:         true,
+		CanSendDocuments:      true,
+		CanSendPhotos:         true,
+		CanSendVideos:         true,
+		CanSendVideoNotes:     true,
+		CanSendVoiceNotes:     true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+		CanChangeInfo:         true,
+		CanInviteUsers:        true,
+		CanPinMessages:        true,
+		CanManageTopics:       true,
+	}
+}
+
+// AdminRights see https://core.telegram.org/bots/api#promotechatmember
+type AdminRights struct {
+	IsAnonymous         bool `json:"is_anonymous"`
+	CanManageChat       bool `json:"can_manage_chat"`
+	CanDeleteMessages   bool `json:"can_delete_messages"`
+	CanManageVideoChats bool `json:"can_manage_video_chats"`
+	CanRestrictMembers  bool `json:"can_restrict_members"`
+	CanPromoteMembers   bool `json:"can_promote_members"`
+	CanChangeInfo       bool `json:"can_change_info"`
+	CanInviteUsers      bool `json:"can_invite_users"`
+	CanPostStories      bool `json:"can_post_stories"`
+	CanEditStories      bool `json:"can_edit_stories"`
+	CanDeleteStories    bool `json:"can_delete_stories"`
+	CanPostMessages     bool `json:"can_post_messages"`
+	CanEditMessages     bool `json:"can_edit_messages"`
+	CanPinMessages      bool `json:"can_pin_messages"`
+	CanManageTopics     bool `json:"can_manage_topics"`
+}
+
+var durationPart = regexp.MustCompile(`^(\d+)(w|d|h|m|s)`)
+
+// ParseDuration parses durations like "2h30m", "1d" or "2w".
+// An empty string is zero.
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"w": 7 * 24 * time.Hour,
+		"d": 24 * time.Hour,
+		"h": time.Hour,
+		"m": time.Minute,
+		"s": time.Second,
+	}
+	rest := strings.ToLower(strings.TrimSpace(s))
+	var d time.Duration
+	for rest != "" {
+		match := durationPart.FindStringSubmatch(rest)
+		if match == nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseInt(match[1], 10, 64)
+		unit := units[match[2]]
+		if err != nil || time.Duration(n) > (math.MaxInt64-d)/unit {
+			return 0, fmt.Errorf("duration %q out of range", s)
+		}
+		d += time.Duration(n) * unit
+		rest = rest[len(match[0]):]
+	}
+	return d, nil
+}
+
+// UntilDate returns the until_date for a restriction lasting d, or 0 for
+// forever when d is zero. Durations shorter than MinRestriction are
+// raised to it; durations longer than MaxRestriction are an error,
+// Telegram would take them as forever.
+func UntilDate(d time.Duration) (int64, error) {
+	switch {
+	case d == 0:
+		return 0, nil
+	case d < 0 || d > MaxRestriction:
+		return 0, fmt.Errorf("restriction of %s out of range, use no duration for forever", d)
+	case d < MinRestriction:
+		d = MinRestriction
+	}
+	return time.Now().Add(d).Unix(), nil
+}
+
+// untilDuration parses a duration string to an until_date.
+func untilDuration(duration string) (int64, error) {
+	d, err := ParseDuration(duration)
+	if err != nil {
+		return 0, err
+	}
+	return UntilDate(d)
+}
+
+// Restrict sets the permissions of a user for duration, e.g. "2h30m".
+// An empty duration restricts forever.
+func (bot *Bot) Restrict(chatID int64, userID int64, permissions ChatPermissions, duration string) (Update, error) {
+	untilDate, err := untilDuration(duration)
+	if err != nil {
+		return Update{}, err
+	}
+	return bot.MakeRequest("restrictChatMember", JSONBody{
+		"chat_id":     chatID,
+		"user_id":     userID,
+		"permissions": permissions,
+		"until_date":  untilDate,
+
+		"use_independent_chat_permissions": true,
+	})
+}
+
+// Mute takes all permissions of a user for duration, see Restrict.
+func (bot *Bot) Mute(chatID int64, userID int64, duration string) (Update, error) {
+	return bot.Restrict(chatID, userID, ChatPermissions{}, duration)
+}
+
+// Unmute gives a muted user all permissions back. Permissions denied to
+// all members of the chat still apply.
+func (bot *Bot) Unmute(chatID int64, userID int64) (Update, error) {
+	return bot.Restrict(chatID, userID, AllPermissions(), "")
+}
+
+// Ban bans a user forever.
+func (bot *Bot) Ban(chatID int64, userID int64) (Update, error) {
+	return bot.BanChatMember(chatID, userID, 0, nil)
+}
+
+// TempBan bans a user for duration, e.g. "1d". An empty duration bans
+// forever; see UntilDate for the range.
+func (bot *Bot) TempBan(chatID int64, userID int64, duration string) (Update, error) {
+	untilDate, err := untilDuration(duration)
+	if err != nil {
+		return Update{}, err
+	}
+	return bot.BanChatMember(chatID, userID, untilDate, nil)
+}
+
+// Unban lifts a ban without removing users who are in the chat.
+func (bot *Bot) Unban(chatID int64, userID int64) (Update, error) {
+	return bot.MakeRequest("unbanChatMember", JSONBody{
+		"chat_id":        chatID,
+		"user_id":        userID,
+		"only_if_banned": true,
+	})
+}
+
+// Kick removes a user from the chat, allowing them to join again.
+func (bot *Bot) Kick(chatID int64, userID int64) (Update, error) {
+	if _, err := bot.BanChatMember(chatID, userID, 0, nil); err != nil {
+		return Update{}, err
+	}
+	return bot.UnbanChatMember(chatID, userID)
+}
+
+// PromoteChatMember see https://core.telegram.org/bots/api#promotechatmember
+func (bot *Bot) PromoteChatMember(chatID int64, userID int64, rights AdminRights) (Update, error) {
+	return bot.MakeRequest("promoteChatMember", mergeJSON(toJSONBody(rights), JSONBody{
+		"chat_id": chatID,
+		"user_id": userID,
+	}))
+}
+
+// Promote makes a user an administrator with the given rights.
+func (bot *Bot) Promote(chatID int64, userID int64, rights AdminRights) (Update, error) {
+	return bot.PromoteChatMember(chatID, userID, rights)
+}
+
+// Demote takes all administrator rights of a user.
+func (bot *Bot) Demote(chatID int64, userID int64) (Update, error) {
+	return bot.PromoteChatMember(chatID, userID, AdminRights{})
+}
+
+// Target returns the user a moderation command is aimed at: the sender
+// of the replied message, a text mention, or a numeric id as the first
+// argument of the command. @username mentions can't be resolved by the
+// Bot API, so they are reported as an error.
+func (update Update) Target() (int64, error) {
+	message, err := update.Message()
+	if err != nil {
+		return 0, err
+	}
+	if id := message.Get("reply_to_message.from.id").Int(); id != 0 {
+		return id, nil
+	}
+	for _, entity := range update.Entities() {
+		switch entity.Get("type").String() {
+		case "text_mention":
+			return entity.Get("user.id").Int(), nil
+		case "mention":
+			return 0, fmt.Errorf("can't resolve a @username, reply to the user instead")
+		}
+	}
+	_, payload := update.Command()
+	if fields := strings.Fields(payload); len(fields) > 0 {
+		if id, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("target user is not found")
+}
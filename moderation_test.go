@@ -0,0 +1,73 @@
+package easytgbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":      0,
+		"2h30m": 2*time.Hour + 30*time.Minute,
+		"1d":    24 * time.Hour,
+		"2w1d":  15 * 24 * time.Hour,
+		"45s":   45 * time.Second,
+	}
+	for in, want := range cases {
+		if got, err := ParseDuration(in); err != nil || got != want {
+			t.Errorf("%q: got %v (%v), want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseDuration("2 hours"); err == nil {
+		t.Error("expected an invalid duration")
+	}
+	if _, err := ParseDuration("99999999999999w"); err == nil {
+		t.Error("expected an overflowing duration to fail")
+	}
+}
+
+func TestUntilDate(t *testing.T) {
+	if until, err := UntilDate(0); err != nil || until != 0 {
+		t.Errorf("no duration must be forever, got %d (%v)", until, err)
+	}
+	if until, err := UntilDate(10 * time.Second); err != nil || until < time.Now().Add(29*time.Second).Unix() {
+		t.Errorf("short durations must be raised to MinRestriction, got %d (%v)", until, err)
+	}
+	if _, err := UntilDate(400 * 24 * time.Hour); err == nil {
+		t.Error("expected an error for a too long duration")
+	}
+	if until, err := UntilDate(time.Hour); err != nil || until < time.Now().Add(59*time.Minute).Unix() {
+		t.Errorf("unexpected until date %d (%v)", until, err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	if _, err := bot.Mute(-100, 7, "2h"); err != nil {
+		t.Fatal(err)
+	}
+	call := api.Calls()[0]
+	permissions := call.Params["permissions"].(map[string]interface{})
+	if call.Method != "restrictChatMember" || permissions["can_send_messages"] != false || call.Params["until_date"].(float64) == 0 {
+		t.Errorf("unexpected call %+v", call)
+	}
+	if _, err := bot.Ban(-100, 7); err != nil || api.Calls()[1].Method != "banChatMember" {
+		t.Errorf("unexpected ban call %+v (%v)", api.Calls()[1], err)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	cases := map[string]int64{
+		`{"message":{"text":"/ban","entities":[{"offset":0,"length":4,"type":"bot_command"}],"reply_to_message":{"from":{"id":7}}}}`:                                7,
+		`{"message":{"text":"/ban Lukin","entities":[{"offset":0,"length":4,"type":"bot_command"},{"offset":5,"length":5,"type":"text_mention","user":{"id":8}}]}}`: 8,
+		`{"message":{"text":"/ban 9 spam","entities":[{"offset":0,"length":4,"type":"bot_command"}]}}`:                                                              9,
+	}
+	for body, want := range cases {
+		if got, err := NewUpdate(body).Target(); err != nil || got != want {
+			t.Errorf("%s: got %d (%v), want %d", body, got, err, want)
+		}
+	}
+	if _, err := NewUpdate(`{"message":{"text":"/ban @lukin","entities":[{"offset":0,"length":4,"type":"bot_command"},{"offset":5,"length":6,"type":"mention"}]}}`).Target(); err == nil {
+		t.Error("expected @username to be unresolvable")
+	}
+}
@@ -0,0 +1,188 @@
+package easytgbot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PreCheckoutTimeout is the default time a pre-checkout validation may
+// take. Telegram cancels the payment if the query is not answered
+// within 10 seconds.
+const PreCheckoutTimeout = 8 * time.Second
+
+// preCheckoutDeadline is the time Telegram waits for the answer to a
+// pre-checkout query.
+var preCheckoutDeadline = 10 * time.Second
+
+// LabeledPrice see https://core.telegram.org/bots/api#labeledprice
+type LabeledPrice struct {
+	Label string `json:"label"`
+	// Amount in the smallest units of the currency
+	Amount int64 `json:"amount"`
+}
+
+// ShippingOption see https://core.telegram.org/bots/api#shippingoption
+type ShippingOption struct {
+	ID     string         `json:"id"`
+	Title  string         `json:"title"`
+	Prices []LabeledPrice `json:"prices"`
+}
+
+// Invoice holds the fields of sendInvoice and createInvoiceLink.
+// Leave ProviderToken empty and use the "XTR" currency for payments
+// in Telegram Stars.
+type Invoice struct {
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Payload       string         `json:"payload"`
+	ProviderToken string         `json:"provider_token,omitempty"`
+	Currency      string         `json:"currency"`
+	Prices        []LabeledPrice `json:"prices"`
+
+	// SubscriptionPeriod in seconds, createInvoiceLink only
+	SubscriptionPeriod int64 `json:"subscription_period,omitempty"`
+
+	MaxTipAmount        int64   `json:"max_tip_amount,omitempty"`
+	SuggestedTipAmounts []int64 `json:"suggested_tip_amounts,omitempty"`
+	ProviderData        string  `json:"provider_data,omitempty"`
+	PhotoURL            string  `json:"photo_url,omitempty"`
+
+	NeedName            bool `json:"need_name,omitempty"`
+	NeedPhoneNumber     bool `json:"need_phone_number,omitempty"`
+	NeedEmail           bool `json:"need_email,omitempty"`
+	NeedShippingAddress bool `json:"need_shipping_address,omitempty"`
+	IsFlexible          bool `json:"is_flexible,omitempty"`
+}
+
+// SendInvoice see https://core.telegram.org/bots/api#sendinvoice
+func (bot *Bot) SendInvoice(chatID int64, invoice Invoice, extra JSONBody) (Update, error) {
+	return bot.MakeRequest("sendInvoice", mergeJSON(mergeJSON(toJSONBody(invoice), JSONBody{
+		"chat_id": chatID,
+	}), extra))
+}
+
+// CreateInvoiceLink see https://core.telegram.org/bots/api#createinvoicelink
+func (bot *Bot) CreateInvoiceLink(invoice Invoice, extra JSONBody) (string, error) {
+	res, err := bot.MakeRequest("createInvoiceLink", mergeJSON(toJSONBody(invoice), extra))
+	if err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+// AnswerShippingQuery see https://core.telegram.org/bots/api#answershippingquery
+//
+// A non-empty errorMessage rejects the shipping address.
+func (bot *Bot) AnswerShippingQuery(queryID string, options []ShippingOption, errorMessage string) (Update, error) {
+	return bot.MakeRequest("answerShippingQuery", shippingAnswer(JSONBody{
+		"shipping_query_id": queryID,
+	}, options, errorMessage))
+}
+
+// AnswerPreCheckoutQuery see https://core.telegram.org/bots/api#answerprecheckoutquery
+//
+// A non-empty errorMessage rejects the payment.
+func (bot *Bot) AnswerPreCheckoutQuery(queryID string, errorMessage string) (Update, error) {
+	return bot.MakeRequest("answerPreCheckoutQuery", preCheckoutAnswer(JSONBody{
+		"pre_checkout_query_id": queryID,
+	}, errorMessage))
+}
+
+// RefundStarPayment see https://core.telegram.org/bots/api#refundstarpayment
+func (bot *Bot) RefundStarPayment(userID int64, chargeID string) (Update, error) {
+	return bot.MakeRequest("refundStarPayment", JSONBody{
+		"user_id":                    userID,
+		"telegram_payment_charge_id": chargeID,
+	})
+}
+
+// AnswerShippingQuery answers the shipping query of the update.
+func (update Update) AnswerShippingQuery(options []ShippingOption, errorMessage string) JSONBody {
+	return shippingAnswer(JSONBody{
+		"method":            "answerShippingQuery",
+		"shipping_query_id": update.Get("shipping_query.id").String(),
+	}, options, errorMessage)
+}
+
+// AnswerPreCheckoutQuery answers the pre-checkout query of the update.
+func (update Update) AnswerPreCheckoutQuery(errorMessage string) JSONBody {
+	return preCheckoutAnswer(JSONBody{
+		"method":                "answerPreCheckoutQuery",
+		"pre_checkout_query_id": update.Get("pre_checkout_query.id").String(),
+	}, errorMessage)
+}
+
+func shippingAnswer(body JSONBody, options []ShippingOption, errorMessage string) JSONBody {
+	if errorMessage != "" {
+		body["ok"] = false
+		body["error_message"] = errorMessage
+	} else {
+		body["ok"] = true
+		body["shipping_options"] = options
+	}
+	return body
+}
+
+func preCheckoutAnswer(body JSONBody, errorMessage string) JSONBody {
+	body["ok"] = errorMessage == ""
+	if errorMessage != "" {
+		body["error_message"] = errorMessage
+	}
+	return body
+}
+
+// PreCheckoutFunc validates a pre-checkout query. A non-nil error
+// rejects the payment and its message is shown to the user. ctx is
+// canceled when the query is answered, at the latest on the timeout of
+// PreCheckout.
+type PreCheckoutFunc func(ctx context.Context, bot *Bot, update Update) error
+
+// PreCheckout handles pre-checkout queries with validate. The query is
+// answered through the API as soon as validate returns, or rejected once
+// timeout passes so Telegram's deadline is never missed; the result of
+// a validation returning later is ignored. The answer request gets the
+// time left until Telegram's deadline, counted from when the query is
+// handled, rather than Bot.Timeout. A zero timeout means
+// PreCheckoutTimeout.
+func (bot *Bot) PreCheckout(timeout time.Duration, validate PreCheckoutFunc) {
+	if timeout <= 0 {
+		timeout = PreCheckoutTimeout
+	}
+	bot.Handle("pre_checkout_query", func(_ interface{}, bot *Bot, update Update) JSONBody {
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(timeout))
+		defer cancel()
+		done := make(chan error, 1)
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					done <- fmt.Errorf("payment validation failed")
+					bot.logger.Error("pre checkout: validation panicked", "error", err)
+				}
+			}()
+			done <- validate(ctx, bot, update)
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = fmt.Errorf("payment validation timed out, please try again")
+		}
+
+		errorMessage := ""
+		if err != nil {
+			errorMessage = err.Error()
+		}
+		answerCtx, cancelAnswer := context.WithDeadline(context.Background(), start.Add(preCheckoutDeadline))
+		defer cancelAnswer()
+		answer := preCheckoutAnswer(JSONBody{
+			"pre_checkout_query_id": update.Get("pre_checkout_query.id").String(),
+		}, errorMessage)
+		if _, err := bot.makeRequest(answerCtx, "answerPreCheckoutQuery", answer); err != nil {
+			bot.logger.Warn("pre checkout: answer", "error", err)
+		}
+		return JSONBody{}
+	})
+}
@@ -0,0 +1,93 @@
+package easytgbot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSendInvoice(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	_, err := bot.SendInvoice(7, Invoice{
+		Title:       "Pro",
+		Description: "Monthly subscription",
+		Payload:     "pro-1",
+		Currency:    "XTR",
+		Prices:      []LabeledPrice{{Label: "Pro", Amount: 100}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := api.Calls()[0]
+	if call.Method != "sendInvoice" || call.Params["chat_id"] != float64(7) || call.Params["currency"] != "XTR" {
+		t.Errorf("unexpected call %+v", call)
+	}
+	if _, ok := call.Params["provider_token"]; ok {
+		t.Error("empty provider_token should be omitted")
+	}
+}
+
+func TestPreCheckout(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	canceled := make(chan bool, 1)
+	bot.PreCheckout(20*time.Millisecond, func(ctx context.Context, bot *Bot, update Update) error {
+		switch update.Get("pre_checkout_query.invoice_payload").String() {
+		case "slow":
+			select {
+			case <-ctx.Done():
+				canceled <- true
+			case <-time.After(time.Second):
+				canceled <- false
+			}
+		case "sold-out":
+			return fmt.Errorf("sold out")
+		}
+		return nil
+	})
+
+	cases := []struct {
+		payload string
+		ok      bool
+	}{
+		{"pro-1", true},
+		{"sold-out", false},
+		{"slow", false},
+	}
+	for i, c := range cases {
+		update := NewUpdate(fmt.Sprintf(`{"pre_checkout_query":{"id":"q%d","from":{"id":7},"currency":"XTR","total_amount":100,"invoice_payload":%q}}`, i, c.payload))
+		if _, err := bot.ApplyHandlers(nil, update); err != nil {
+			t.Fatal(err)
+		}
+		call := api.Calls()[i]
+		if call.Method != "answerPreCheckoutQuery" || call.Params["ok"] != c.ok {
+			t.Errorf("%s: unexpected answer %+v", c.payload, call)
+		}
+	}
+	if !<-canceled {
+		t.Error("the slow validation was not canceled")
+	}
+}
+
+func TestPreCheckoutDeadline(t *testing.T) {
+	defer func(d time.Duration) { preCheckoutDeadline = d }(preCheckoutDeadline)
+	preCheckoutDeadline = 100 * time.Millisecond
+
+	bot, api := newTestBot(t, Settings{})
+	bot.Timeout = 5 * time.Second
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		time.Sleep(time.Second)
+		return true, nil
+	}
+	bot.PreCheckout(50*time.Millisecond, func(ctx context.Context, bot *Bot, update Update) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	bot.ApplyHandlers(nil, NewUpdate(`{"pre_checkout_query":{"id":"q1","from":{"id":7},"currency":"XTR","total_amount":100,"invoice_payload":"slow"}}`))
+	// the answer only gets what is left of the deadline, not bot.Timeout
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("answering took %s, past the deadline", elapsed)
+	}
+}
@@ -0,0 +1,155 @@
+package easytgbot
+
+import (
+	"sort"
+	"sync"
+)
+
+// SendPoll see https://core.telegram.org/bots/api#sendpoll
+func (bot *Bot) SendPoll(chatID int64, question string, options []string, extra JSONBody) (Update, error) {
+	return bot.MakeRequest("sendPoll", mergeJSON(JSONBody{
+		"chat_id":  chatID,
+		"question": question,
+		"options":  pollOptions(options),
+	}, extra))
+}
+
+// SendQuiz sends a quiz poll whose correct answer is the option at
+// correctOptionID. Set "explanation" in extra to explain the answer.
+func (bot *Bot) SendQuiz(chatID int64, question string, options []string, correctOptionID int, extra JSONBody) (Update, error) {
+	return bot.SendPoll(chatID, question, options, mergeJSON(JSONBody{
+		"type":              "quiz",
+		"correct_option_id": correctOptionID,
+	}, extra))
+}
+
+// StopPoll see https://core.telegram.org/bots/api#stoppoll
+func (bot *Bot) StopPoll(chatID int64, messageID int64, extra JSONBody) (Update, error) {
+	return bot.MakeRequest("stopPoll", mergeJSON(JSONBody{
+		"chat_id":    chatID,
+		"message_id": messageID,
+	}, extra))
+}
+
+func pollOptions(options []string) []JSONBody {
+	res := make([]JSONBody, 0, len(options))
+	for _, option := range options {
+		res = append(res, JSONBody{"text": option})
+	}
+	return res
+}
+
+// PollAnswer is a vote in a non-anonymous poll.
+type PollAnswer struct {
+	PollID string
+	// User who voted, empty when voting on behalf of VoterChat
+	User      Update
+	VoterChat Update
+	// OptionIDs is empty when the vote was retracted
+	OptionIDs []int64
+}
+
+// PollAnswer returns the poll answer of a poll_answer update.
+func (update Update) PollAnswer() (PollAnswer, bool) {
+	answer := update.Get("poll_answer")
+	if !answer.Exists() {
+		return PollAnswer{}, false
+	}
+	res := PollAnswer{
+		PollID:    answer.Get("poll_id").String(),
+		User:      answer.Get("user"),
+		VoterChat: answer.Get("voter_chat"),
+		OptionIDs: []int64{},
+	}
+	for _, id := range answer.Get("option_ids").Array() {
+		res.OptionIDs = append(res.OptionIDs, id.Int())
+	}
+	return res, true
+}
+
+// PollTally counts poll answers in memory for non-anonymous polls.
+type PollTally struct {
+	mutex sync.Mutex
+	votes map[string]map[int64][]int64
+}
+
+// NewPollTally creates a tally.
+func NewPollTally() *PollTally {
+	return &PollTally{
+		votes: make(map[string]map[int64][]int64),
+	}
+}
+
+// Record counts the vote of a poll_answer update, replacing the
+// previous vote of the voter. It reports whether update was a vote.
+func (t *PollTally) Record(update Update) bool {
+	answer, ok := update.PollAnswer()
+	if !ok {
+		return false
+	}
+	voter := answer.User.Get("id").Int()
+	if voter == 0 {
+		voter = answer.VoterChat.Get("id").Int()
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	poll, ok := t.votes[answer.PollID]
+	if !ok {
+		poll = make(map[int64][]int64)
+		t.votes[answer.PollID] = poll
+	}
+	if len(answer.OptionIDs) == 0 {
+		delete(poll, voter)
+	} else {
+		poll[voter] = answer.OptionIDs
+	}
+	return true
+}
+
+// Counts returns the number of votes per option id.
+func (t *PollTally) Counts(pollID string) map[int64]int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	counts := map[int64]int{}
+	for _, options := range t.votes[pollID] {
+		for _, option := range options {
+			counts[option]++
+		}
+	}
+	return counts
+}
+
+// Voters returns the ids of users who chose the option, in ascending order.
+func (t *PollTally) Voters(pollID string, optionID int64) []int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	voters := []int64{}
+	for voter, options := range t.votes[pollID] {
+		for _, option := range options {
+			if option == optionID {
+				voters = append(voters, voter)
+				break
+			}
+		}
+	}
+	sort.Slice(voters, func(i, j int) bool { return voters[i] < voters[j] })
+	return voters
+}
+
+// Forget drops the votes of a poll, e.g. after StopPoll.
+func (t *PollTally) Forget(pollID string) {
+	t.mutex.Lock()
+	delete(t.votes, pollID)
+	t.mutex.Unlock()
+}
+
+// Middleware records poll answers before the handler runs; add it with
+// Bot.UseAll to record answers without a handler too.
+func (t *PollTally) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			t.Record(update)
+			return next(context, bot, update)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package easytgbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPollAnswerRouting(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	tally := NewPollTally()
+	bot.Use(tally.Middleware())
+	bot.Handle("poll_answer", func(context interface{}, bot *Bot, update Update) JSONBody {
+		answer, _ := update.PollAnswer()
+		return JSONBody{"voter": update.FromID(), "options": answer.OptionIDs}
+	})
+
+	votes := []string{
+		`{"poll_answer":{"poll_id":"p1","user":{"id":7},"option_ids":[0]}}`,
+		`{"poll_answer":{"poll_id":"p1","user":{"id":8},"option_ids":[0,2]}}`,
+		`{"poll_answer":{"poll_id":"p1","user":{"id":9},"option_ids":[1]}}`,
+		`{"poll_answer":{"poll_id":"p1","user":{"id":9},"option_ids":[]}}`,
+	}
+	for _, vote := range votes {
+		if _, err := bot.ApplyHandlers(nil, NewUpdate(vote)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, _ := bot.ApplyHandlers(nil, NewUpdate(votes[1]))
+	if res["voter"] != int64(8) || !reflect.DeepEqual(res["options"], []int64{0, 2}) {
+		t.Errorf("unexpected poll answer %v", res)
+	}
+
+	if counts := tally.Counts("p1"); !reflect.DeepEqual(counts, map[int64]int{0: 2, 2: 1}) {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if voters := tally.Voters("p1", 0); !reflect.DeepEqual(voters, []int64{7, 8}) {
+		t.Errorf("unexpected voters %v", voters)
+	}
+}
@@ -0,0 +1,137 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Send executes a handler result, e.g. the result of Update.Reply,
+// as API calls and returns the last result. Results without a method
+// are ignored; failures of a batch are reported as a BatchError.
+func (bot *Bot) Send(res JSONBody) (Update, error) {
+	actions := Actions(res)
+	switch len(actions) {
+	case 0:
+		return Update{}, nil
+	case 1:
+		method, params := splitMethod(actions[0])
+		return bot.MakeRequest(method, params)
+	}
+	return bot.sendActions(actions, -1)
+}
+
+// webhookReply reports whether a handler result can be the reply to a
+// webhook request: multipart uploads and texts to split cannot.
+func webhookReply(method string, params JSONBody) bool {
+	return method != "" && !hasFileUpload(params) && !oversized(method, params)
+}
+
+// Respond delivers a handler result. With a webhook response writer
+// the first action that fits is written as the response and the
+// others are sent through the API. Without one, in polling mode, all
+// of them are sent through the API.
+func (bot *Bot) Respond(w http.ResponseWriter, res JSONBody) error {
+	if w == nil {
+		_, err := bot.Send(res)
+		return err
+	}
+
+	actions := Actions(res)
+	reply := -1
+	var data []byte
+	for i, action := range actions {
+		if method, params := splitMethod(action); webhookReply(method, params) {
+			var err error
+			if data, err = json.Marshal(action); err == nil {
+				reply = i
+				break
+			}
+		}
+	}
+
+	var err error
+	switch {
+	case len(actions) == 1 && reply < 0:
+		method, params := splitMethod(actions[0])
+		_, err = bot.MakeRequest(method, params)
+	case len(actions) > 1:
+		_, err = bot.sendActions(actions, reply)
+	}
+	if reply < 0 {
+		w.WriteHeader(http.StatusOK)
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, writeErr := w.Write(data); err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+// HandleUpdate runs the handler of an update and sends its result
+// through the API, as in polling mode. Updates without a handler are
+// not an error; other errors also go to the OnError handlers.
+func (bot *Bot) HandleUpdate(context interface{}, update Update) error {
+	res, err := bot.ApplyHandlers(context, update)
+	if err != nil {
+		if errors.Is(err, ErrUnhandled) {
+			return nil
+		}
+		return err
+	}
+	if err := bot.Respond(nil, res); err != nil {
+		bot.reportError(err, update)
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP handles webhook requests; the request is the handler
+// context.
+func (bot *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil || !json.Valid(data) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	update := NewUpdate(string(data))
+	res, err := bot.ApplyHandlers(r, update)
+	if err != nil {
+		// reported by ApplyHandlers, Telegram must not retry
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := bot.Respond(w, res); err != nil {
+		bot.reportError(err, update)
+	}
+}
+
+// Poll receives updates with GetUpdates and handles them one by one
+// until Stop is called. Errors go to the OnError handlers.
+func (bot *Bot) Poll(context interface{}, params JSONBody) error {
+	if bot.Webhook != "" {
+		return fmt.Errorf("poll: the bot uses a webhook")
+	}
+	if params == nil {
+		params = JSONBody{}
+	}
+	if _, ok := params["offset"]; !ok {
+		params["offset"] = 0
+	}
+	updates, err := bot.GetUpdates(params)
+	if err != nil {
+		return err
+	}
+	for update := range updates {
+		bot.HandleUpdate(context, update)
+	}
+	return nil
+}
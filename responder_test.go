@@ -0,0 +1,109 @@
+package easytgbot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imroc/req"
+)
+
+func TestRespondWebhook(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		if update.Get("message.text").String() == "hi" {
+			return update.Reply("hello", nil)
+		}
+		file, _ := os.Open("go.mod")
+		return JSONBody{
+			"method":   "sendDocument",
+			"chat_id":  update.ChatID(),
+			"document": req.FileUpload{File: file, FieldName: "document", FileName: "go.mod"},
+		}
+	})
+
+	serve := func(text string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"update_id":1,"message":{"message_id":1,"chat":{"id":7,"type":"private"},` + text + `}}`
+		bot.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(`"text":"hi"`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"method":"sendMessage"`) {
+		t.Errorf("got %d %s", w.Code, w.Body)
+	}
+	if len(api.Calls()) != 0 {
+		t.Errorf("webhook reply called the API: %v", api.Calls())
+	}
+
+	w = serve(`"text":"file"`)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("got %d %s", w.Code, w.Body)
+	}
+	if calls := api.Calls(); len(calls) != 1 || calls[0].Method != "sendDocument" || calls[0].Params["document"] != "file" {
+		t.Errorf("unexpected calls %v", calls)
+	}
+
+	if w := serve(`"sticker":{}`); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("got %d %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	bot.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d for a bad body", w.Code)
+	}
+}
+
+func TestRespondPolling(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	bot.Handle("sticker", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return update.Reply("hello", nil)
+	})
+	polls := 0
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		if method != "getUpdates" {
+			return JSONBody{"message_id": 2}, nil
+		}
+		polls++
+		if polls > 1 {
+			bot.Stop()
+			return []JSONBody{}, nil
+		}
+		return []JSONBody{
+			{"update_id": 10, "message": JSONBody{"message_id": 1, "chat": JSONBody{"id": 7, "type": "private"}, "sticker": JSONBody{}}},
+			{"update_id": 11, "message": JSONBody{"message_id": 2, "chat": JSONBody{"id": 7, "type": "private"}, "text": "nothing"}},
+		}, nil
+	}
+	if err := bot.Poll(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := []apiCall{}
+	for _, call := range api.Calls() {
+		if call.Method == "sendMessage" {
+			sent = append(sent, call)
+		}
+	}
+	if len(sent) != 1 || sent[0].Params["text"] != "hello" {
+		t.Errorf("unexpected calls %v", api.Calls())
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	api.reply = func(method string, params JSONBody) (interface{}, *Error) {
+		time.Sleep(50 * time.Millisecond)
+		return true, nil
+	}
+	if _, err := bot.GetMe(); err != nil {
+		t.Fatal(err)
+	}
+	bot.Timeout = 10 * time.Millisecond
+	if _, err := bot.GetMe(); err == nil {
+		t.Error("expected the changed timeout to apply")
+	}
+}
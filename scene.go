@@ -0,0 +1,282 @@
+package easytgbot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SceneStep handles an update for the current step of a scene.
+// The scene moves to the next step afterwards unless the step
+// calls one of the transition methods of state.
+type SceneStep func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody
+
+// Scene is a multi-step conversation with a user in a chat.
+//
+// While a user is in a scene every update they send in that chat goes to
+// the current step, except the cancel command and commands that have a
+// handler, which leave the scene and are routed normally. Sending the
+// command that entered the scene therefore restarts it.
+type Scene struct {
+	Name  string
+	Steps []SceneStep
+
+	// Timeout leaves the scene when the user is idle for longer.
+	// It is checked when the next update arrives. Default: no timeout,
+	// but users idle for 48 hours are dropped from their scene
+	Timeout time.Duration
+
+	// CancelCommand leaves the scene. Default: /cancel
+	CancelCommand string
+
+	// OnCancel replies to the cancel command.
+	OnCancel HandlerFunc
+
+	// OnTimeout replies to the first update after a timeout. Without it
+	// the update is routed normally.
+	OnTimeout HandlerFunc
+}
+
+// sceneStateTTL is how long the state of an idle user is kept.
+const sceneStateTTL = 48 * time.Hour
+
+// SceneState is the state of a user in a scene.
+type SceneState struct {
+	Scene string
+	Step  int
+	Data  JSONBody
+
+	updated time.Time
+	next    int
+	leave   bool
+
+	// mutex serializes the updates of the user in the scene
+	mutex sync.Mutex
+}
+
+// Next moves to the next step, which is the default.
+func (state *SceneState) Next() {
+	state.next = state.Step + 1
+}
+
+// Back moves to the previous step.
+func (state *SceneState) Back() {
+	if state.Step > 0 {
+		state.next = state.Step - 1
+	}
+}
+
+// Goto moves to the given step.
+func (state *SceneState) Goto(step int) {
+	state.next = step
+}
+
+// Stay keeps the current step, e.g. to ask again after invalid input.
+func (state *SceneState) Stay() {
+	state.next = state.Step
+}
+
+// Leave ends the scene after the step.
+func (state *SceneState) Leave() {
+	state.leave = true
+}
+
+// Scene registers a scene with the given steps.
+func (bot *Bot) Scene(name string, steps ...SceneStep) *Scene {
+	scene := &Scene{
+		Name:  name,
+		Steps: steps,
+	}
+	bot.sceneMutex.Lock()
+	bot.scenes[name] = scene
+	bot.sceneMutex.Unlock()
+	return scene
+}
+
+// Enter is a handler that enters the scene, e.g.
+// bot.Handle("/signup", scene.Enter). Errors fail the update.
+func (scene *Scene) Enter(context interface{}, bot *Bot, update Update) JSONBody {
+	res, err := bot.EnterScene(context, update, scene.Name)
+	if err != nil {
+		return Fail(err)
+	}
+	return res
+}
+
+// EnterScene puts the sender of update into the scene, dropping any
+// scene they are in, and runs the first step with update. The step
+// runs without global middleware, which already ran for the handler
+// calling EnterScene; later steps run inside it.
+func (bot *Bot) EnterScene(context interface{}, update Update, name string) (JSONBody, error) {
+	key := sceneKey(update)
+	if key == "" {
+		return JSONBody{}, fmt.Errorf("scene needs a chat and a user")
+	}
+	bot.sceneMutex.Lock()
+	scene, ok := bot.scenes[name]
+	if !ok {
+		bot.sceneMutex.Unlock()
+		return JSONBody{}, fmt.Errorf("scene %s is not found", name)
+	}
+	state := &SceneState{
+		Scene: name,
+		Data:  JSONBody{},
+	}
+	// later updates wait for the first step
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	bot.sweepScenes(time.Now())
+	bot.sceneStates[key] = state
+	bot.sceneMutex.Unlock()
+
+	return bot.runScene(context, update, scene, state, key), nil
+}
+
+// LeaveScene removes the sender of update from their scene.
+func (bot *Bot) LeaveScene(update Update) {
+	bot.sceneMutex.Lock()
+	delete(bot.sceneStates, sceneKey(update))
+	bot.sceneMutex.Unlock()
+}
+
+// SceneState returns the scene state of the sender of update, or nil.
+func (bot *Bot) SceneState(update Update) *SceneState {
+	bot.sceneMutex.Lock()
+	defer bot.sceneMutex.Unlock()
+	return bot.sceneStates[sceneKey(update)]
+}
+
+// applyScene routes update to the scene of its sender.
+func (bot *Bot) applyScene(context interface{}, update Update) (JSONBody, bool) {
+	key := sceneKey(update)
+	if key == "" {
+		return nil, false
+	}
+	scene, state := bot.lockScene(key)
+	if scene == nil {
+		return nil, false
+	}
+	defer state.mutex.Unlock()
+
+	// timeout
+	if scene.Timeout > 0 && time.Since(state.updated) > scene.Timeout {
+		bot.leaveScene(key, state)
+		if scene.OnTimeout == nil {
+			return nil, false
+		}
+		return applyMiddleware(scene.OnTimeout, bot.middleware...)(context, bot, update), true
+	}
+
+	command := bot.command(update)
+	cancel := scene.CancelCommand
+	if cancel == "" {
+		cancel = "/cancel"
+	}
+	if command == cancel {
+		bot.leaveScene(key, state)
+		if scene.OnCancel == nil {
+			return JSONBody{}, true
+		}
+		return applyMiddleware(scene.OnCancel, bot.middleware...)(context, bot, update), true
+	}
+	if command != "" && len(bot.handlers[command]) > 0 {
+		bot.leaveScene(key, state)
+		return nil, false
+	}
+
+	step := func(context interface{}, bot *Bot, update Update) JSONBody {
+		return bot.runScene(context, update, scene, state, key)
+	}
+	return applyMiddleware(step, bot.middleware...)(context, bot, update), true
+}
+
+// runScene runs the current step of state and applies its transition.
+// The caller applies the middleware.
+func (bot *Bot) runScene(context interface{}, update Update, scene *Scene, state *SceneState, key string) JSONBody {
+	if state.Step < 0 || state.Step >= len(scene.Steps) {
+		bot.leaveScene(key, state)
+		return JSONBody{}
+	}
+	step := scene.Steps[state.Step]
+	state.next = state.Step + 1
+	state.leave = false
+
+	res := step(context, bot, update, state)
+
+	if state.leave || state.next < 0 || state.next >= len(scene.Steps) {
+		bot.leaveScene(key, state)
+	} else {
+		state.Step = state.next
+		bot.sceneMutex.Lock()
+		state.updated = time.Now()
+		bot.sceneMutex.Unlock()
+	}
+	return res
+}
+
+// lockScene returns the scene of the user with key and their state,
+// locked, or nil when they are in no scene.
+func (bot *Bot) lockScene(key string) (*Scene, *SceneState) {
+	for {
+		bot.sceneMutex.Lock()
+		state, ok := bot.sceneStates[key]
+		var scene *Scene
+		if ok {
+			scene = bot.scenes[state.Scene]
+		}
+		if ok && scene == nil {
+			delete(bot.sceneStates, key)
+		}
+		bot.sceneMutex.Unlock()
+		if scene == nil {
+			return nil, nil
+		}
+
+		state.mutex.Lock()
+		bot.sceneMutex.Lock()
+		current := bot.sceneStates[key] == state
+		bot.sceneMutex.Unlock()
+		if current {
+			return scene, state
+		}
+		// the user left or changed scenes meanwhile
+		state.mutex.Unlock()
+	}
+}
+
+// sweepScenes drops the states of users idle for longer than
+// sceneStateTTL, or the timeout of their scene when longer. The caller
+// holds sceneMutex.
+func (bot *Bot) sweepScenes(now time.Time) {
+	if now.Sub(bot.sceneSwept) < sceneStateTTL {
+		return
+	}
+	for key, state := range bot.sceneStates {
+		ttl := sceneStateTTL
+		if scene, ok := bot.scenes[state.Scene]; ok && scene.Timeout > ttl {
+			ttl = scene.Timeout
+		}
+		if !state.updated.IsZero() && now.Sub(state.updated) > ttl {
+			delete(bot.sceneStates, key)
+		}
+	}
+	bot.sceneSwept = now
+}
+
+// leaveScene removes state unless the user already moved to another scene.
+func (bot *Bot) leaveScene(key string, state *SceneState) {
+	bot.sceneMutex.Lock()
+	if bot.sceneStates[key] == state {
+		delete(bot.sceneStates, key)
+	}
+	bot.sceneMutex.Unlock()
+}
+
+// sceneKey identifies a user in a chat.
+func sceneKey(update Update) string {
+	chatID, userID := update.ChatID(), update.FromID()
+	if chatID == 0 || userID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", chatID, userID)
+}
@@ -0,0 +1,176 @@
+package easytgbot
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sceneUpdate(text string) Update {
+	entities := ""
+	if text[0] == '/' {
+		entities = fmt.Sprintf(`,"entities":[{"offset":0,"length":%d,"type":"bot_command"}]`, len(text))
+	}
+	return NewUpdate(fmt.Sprintf(`{"message":{"message_id":1,"from":{"id":7},"chat":{"id":7,"type":"private"},"text":%q%s}}`, text, entities))
+}
+
+func TestScene(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	scene := bot.Scene("signup",
+		func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+			return JSONBody{"text": "name?"}
+		},
+		func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+			name := update.Get("message.text").String()
+			if name == "" || name[0] == '!' {
+				state.Stay()
+				return JSONBody{"text": "name?"}
+			}
+			state.Data["name"] = name
+			return JSONBody{"text": "age?"}
+		},
+		func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+			return JSONBody{"text": "bye " + state.Data["name"].(string)}
+		},
+	)
+	scene.OnCancel = func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"text": "cancelled"}
+	}
+	bot.Handle("/signup", scene.Enter)
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return JSONBody{"text": "echo"}
+	})
+
+	steps := []struct{ in, want string }{
+		{"hello", "echo"},
+		{"/signup", "name?"},
+		{"!bad", "name?"},
+		{"lukin", "age?"},
+		{"/signup", "name?"},
+		{"lukin", "age?"},
+		{"18", "bye lukin"},
+		{"hello", "echo"},
+		{"/signup", "name?"},
+		{"/cancel", "cancelled"},
+		{"hello", "echo"},
+	}
+	for i, step := range steps {
+		res, err := bot.ApplyHandlers(nil, sceneUpdate(step.in))
+		if err != nil || res["text"] != step.want {
+			t.Fatalf("step %d %q: got %v (%v), want %q", i, step.in, res["text"], err, step.want)
+		}
+	}
+}
+
+func TestSceneTimeout(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	scene := bot.Scene("wait",
+		func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+			return JSONBody{"text": "first"}
+		},
+		func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+			return JSONBody{"text": "second"}
+		},
+	)
+	scene.Timeout = time.Millisecond
+	if _, err := bot.EnterScene(nil, sceneUpdate("go"), "wait"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := bot.ApplyHandlers(nil, sceneUpdate("late")); err == nil {
+		t.Error("expected the scene to time out")
+	}
+	if bot.SceneState(sceneUpdate("late")) != nil {
+		t.Error("expected the scene state to be removed")
+	}
+}
+
+func TestSceneConcurrent(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	count := func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+		state.Data["count"] = state.Data["count"].(int) + 1
+		state.Stay()
+		return JSONBody{}
+	}
+	bot.Scene("count", func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+		state.Data["count"] = 0
+		return JSONBody{}
+	}, count)
+	if _, err := bot.EnterScene(nil, sceneUpdate("go"), "count"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bot.ApplyHandlers(nil, sceneUpdate("more"))
+		}()
+	}
+	wg.Wait()
+	if state := bot.SceneState(sceneUpdate("more")); state == nil || state.Data["count"] != 20 || state.Step != 1 {
+		t.Errorf("unexpected scene state %+v", state)
+	}
+
+	res := (&Scene{Name: "missing"}).Enter(nil, bot, sceneUpdate("go"))
+	if ResultError(res) == nil {
+		t.Error("expected entering an unknown scene to fail")
+	}
+}
+
+func TestSceneSession(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	bot.UseSession(SessionOptions{})
+	step := func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+		session := bot.Session(update)
+		if session == nil {
+			return JSONBody{"text": "no session"}
+		}
+		visits, _ := session.Data["visits"].(float64)
+		session.Data["visits"] = visits + 1
+		return JSONBody{"text": fmt.Sprint(visits + 1)}
+	}
+	scene := bot.Scene("visits", step, step)
+	bot.Handle("/go", scene.Enter)
+
+	done := make(chan []interface{})
+	go func() {
+		texts := []interface{}{}
+		for _, text := range []string{"/go", "again"} {
+			res, _ := bot.ApplyHandlers(nil, sceneUpdate(text))
+			texts = append(texts, res["text"])
+		}
+		done <- texts
+	}()
+	select {
+	case texts := <-done:
+		if fmt.Sprint(texts) != "[1 2]" {
+			t.Errorf("unexpected replies %v", texts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("entering a scene with sessions deadlocked")
+	}
+}
+
+func TestSceneSweep(t *testing.T) {
+	bot, _ := New("token", Settings{})
+	wait := func(context interface{}, bot *Bot, update Update, state *SceneState) JSONBody {
+		return JSONBody{}
+	}
+	bot.Scene("idle", wait, wait)
+	if _, err := bot.EnterScene(nil, sceneUpdate("go"), "idle"); err != nil {
+		t.Fatal(err)
+	}
+	bot.SceneState(sceneUpdate("go")).updated = time.Now().Add(-sceneStateTTL - time.Minute)
+	bot.sceneSwept = time.Time{}
+
+	other := NewUpdate(`{"message":{"message_id":1,"from":{"id":8},"chat":{"id":8,"type":"private"},"text":"go"}}`)
+	if _, err := bot.EnterScene(nil, other, "idle"); err != nil {
+		t.Fatal(err)
+	}
+	if bot.SceneState(sceneUpdate("go")) != nil || bot.SceneState(other) == nil {
+		t.Error("expected only the idle scene state to be dropped")
+	}
+}
@@ -0,0 +1,495 @@
+package easytgbot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestJob is the job kind sending its payload as a Bot API request,
+// e.g. {"method": "deleteMessage", "chat_id": 1, "message_id": 2}.
+const RequestJob = "request"
+
+// JobFunc runs a scheduled job with its payload.
+type JobFunc func(bot *Bot, payload JSONBody) error
+
+// Job is a scheduled run of a registered job kind. Jobs are saved by
+// kind and payload, so payloads must survive a JSON round trip.
+type Job struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Payload JSONBody  `json:"payload"`
+	Next    time.Time `json:"next"`
+	// Cron is the expression of recurring jobs.
+	Cron string `json:"cron,omitempty"`
+}
+
+// SchedulerOptions configures a scheduler.
+type SchedulerOptions struct {
+	// File keeps pending jobs across restarts.
+	File string
+	// Location is the default timezone of cron expressions;
+	// "CRON_TZ=Europe/Berlin 0 9 * * *" overrides it. Default: time.Local
+	Location *time.Location
+	// OnError is called when a job fails or panics, with a PanicError.
+	// Default: log the error
+	OnError func(job Job, err error)
+}
+
+// Scheduler runs one-off and recurring jobs until the bot stops.
+// One-off jobs run at most once: they are removed before running.
+type Scheduler struct {
+	bot   *Bot
+	opts  SchedulerOptions
+	kinds map[string]JobFunc
+	jobs  map[string]*Job
+	crons map[string]*cronSchedule
+
+	wake    chan struct{}
+	stop    chan struct{}
+	started bool
+	stopped bool
+	running sync.WaitGroup
+	mutex   sync.Mutex
+}
+
+// NewScheduler creates a scheduler, loading the jobs saved in
+// opts.File. Register the job kinds, then call Start.
+func (bot *Bot) NewScheduler(opts SchedulerOptions) (*Scheduler, error) {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(job Job, err error) {
+			bot.logger.Error("scheduler: job failed", "job", job.ID, "kind", job.Kind, "error", err)
+		}
+	}
+	s := &Scheduler{
+		bot:   bot,
+		opts:  opts,
+		kinds: make(map[string]JobFunc),
+		jobs:  make(map[string]*Job),
+		crons: make(map[string]*cronSchedule),
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+	s.kinds[RequestJob] = func(bot *Bot, payload JSONBody) error {
+		method, params := splitMethod(payload)
+		_, err := bot.MakeRequest(method, params)
+		return err
+	}
+
+	if opts.File != "" {
+		data, err := ioutil.ReadFile(opts.File)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			jobs := []*Job{}
+			if err := json.Unmarshal(data, &jobs); err != nil {
+				return nil, fmt.Errorf("scheduler: %s: %s", opts.File, err)
+			}
+			now := time.Now()
+			for _, job := range jobs {
+				if job.Cron != "" {
+					schedule, err := parseCron(job.Cron, opts.Location)
+					if err != nil {
+						return nil, fmt.Errorf("scheduler: job %s: %s", job.ID, err)
+					}
+					s.crons[job.ID] = schedule
+					// skip runs missed while stopped
+					if job.Next.Before(now) {
+						job.Next = schedule.next(now)
+					}
+				}
+				s.jobs[job.ID] = job
+			}
+		}
+	}
+	return s, nil
+}
+
+// Register sets the function running jobs of a kind.
+func (s *Scheduler) Register(kind string, fn JobFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.kinds[kind] = fn
+}
+
+// At schedules a job at t.
+func (s *Scheduler) At(t time.Time, kind string, payload JSONBody) (Job, error) {
+	return s.add(&Job{Kind: kind, Payload: payload, Next: t}, nil)
+}
+
+// After schedules a job after d.
+func (s *Scheduler) After(d time.Duration, kind string, payload JSONBody) (Job, error) {
+	return s.At(time.Now().Add(d), kind, payload)
+}
+
+// Cron schedules a recurring job from a cron expression with the
+// fields minute, hour, day of month, month and day of week, or one of
+// @yearly, @monthly, @weekly, @daily and @hourly.
+func (s *Scheduler) Cron(expr string, kind string, payload JSONBody) (Job, error) {
+	schedule, err := parseCron(expr, s.opts.Location)
+	if err != nil {
+		return Job{}, err
+	}
+	next := schedule.next(time.Now())
+	if next.IsZero() {
+		return Job{}, fmt.Errorf("scheduler: %q never runs", expr)
+	}
+	return s.add(&Job{Kind: kind, Payload: payload, Next: next, Cron: expr}, schedule)
+}
+
+func (s *Scheduler) add(job *Job, schedule *cronSchedule) (Job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Job{}, err
+	}
+	job.ID = hex.EncodeToString(id)
+	if job.Payload == nil {
+		job.Payload = JSONBody{}
+	}
+
+	s.mutex.Lock()
+	if _, ok := s.kinds[job.Kind]; !ok {
+		s.mutex.Unlock()
+		return Job{}, fmt.Errorf("scheduler: unknown job kind %s", job.Kind)
+	}
+	s.jobs[job.ID] = job
+	if schedule != nil {
+		s.crons[job.ID] = schedule
+	}
+	err := s.save()
+	copied := *job
+	s.mutex.Unlock()
+
+	s.notify()
+	return copied, err
+}
+
+// Cancel removes a pending job.
+func (s *Scheduler) Cancel(id string) bool {
+	s.mutex.Lock()
+	_, ok := s.jobs[id]
+	if ok {
+		delete(s.jobs, id)
+		delete(s.crons, id)
+		if err := s.save(); err != nil {
+			s.bot.logger.Error("scheduler: save", "error", err)
+		}
+	}
+	s.mutex.Unlock()
+	s.notify()
+	return ok
+}
+
+// Jobs returns the pending jobs by next run.
+func (s *Scheduler) Jobs() []Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Next.Before(jobs[j].Next) })
+	return jobs
+}
+
+// Start runs jobs in the background until Stop or bot.Stop is called.
+// Jobs that were due while stopped run right away.
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.loop()
+}
+
+// Stop stops the scheduler and waits for running jobs.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	s.mutex.Unlock()
+	s.running.Wait()
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) loop() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		wait := s.runDue(time.Now())
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.bot.shutdownChannel:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// runDue starts the due jobs and returns the time until the next one.
+func (s *Scheduler) runDue(now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped {
+		return time.Hour
+	}
+
+	wait := time.Hour
+	changed := false
+	for id, job := range s.jobs {
+		if job.Next.After(now) {
+			if d := job.Next.Sub(now); d < wait {
+				wait = d
+			}
+			continue
+		}
+		run := *job
+		if schedule, ok := s.crons[id]; ok && !schedule.next(now).IsZero() {
+			job.Next = schedule.next(now)
+			if d := job.Next.Sub(now); d < wait {
+				wait = d
+			}
+		} else {
+			delete(s.jobs, id)
+			delete(s.crons, id)
+		}
+		changed = true
+
+		fn := s.kinds[run.Kind]
+		s.running.Add(1)
+		go func() {
+			defer s.running.Done()
+			defer func() {
+				if value := recover(); value != nil {
+					s.opts.OnError(run, &PanicError{Value: value, Stack: debug.Stack()})
+				}
+			}()
+			var err error
+			if fn == nil {
+				err = fmt.Errorf("unknown job kind %s", run.Kind)
+			} else {
+				err = fn(s.bot, run.Payload)
+			}
+			if err != nil {
+				s.opts.OnError(run, err)
+			}
+		}()
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			s.bot.logger.Error("scheduler: save", "error", err)
+		}
+	}
+	return wait
+}
+
+// save writes the pending jobs; the caller holds the mutex.
+func (s *Scheduler) save() error {
+	if s.opts.File == "" {
+		return nil
+	}
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.opts.File, data)
+}
+
+// cronSchedule is a parsed cron expression.
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny and dowAny are set for "*" fields: when both day fields
+	// are restricted, either one matching is enough.
+	domAny, dowAny bool
+	location       *time.Location
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+func parseCron(expr string, location *time.Location) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		parts := strings.SplitN(expr, " ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid cron expression %q", expr)
+		}
+		loc, err := time.LoadLocation(parts[0][strings.Index(parts[0], "=")+1:])
+		if err != nil {
+			return nil, err
+		}
+		location, expr = loc, strings.TrimSpace(parts[1])
+	}
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields", expr)
+	}
+
+	s := &cronSchedule{location: location}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// parseCronField parses lists of values, ranges and steps.
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	value := func(s string) (int, error) {
+		if n, ok := cronNames[strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("invalid cron value %q", s)
+		}
+		return n, nil
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid cron step %q", part)
+			}
+			step, part = n, part[:i]
+		}
+		from, to := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if to, err = value(bounds[1]); err != nil {
+				return nil, err
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid cron range %q", part)
+			}
+		default:
+			n, err := value(part)
+			if err != nil {
+				return nil, err
+			}
+			from = n
+			if step == 1 {
+				to = n
+			}
+		}
+		for n := from; n <= to; n += step {
+			values[n] = true
+		}
+	}
+	return values, nil
+}
+
+// next returns the first run after t, or the zero time when there is
+// none within five years.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		switch {
+		case !s.month[int(m)]:
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchDay(t):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, s.location)
+		case !s.hour[t.Hour()]:
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, s.location)
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
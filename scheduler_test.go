@@ -0,0 +1,127 @@
+package easytgbot
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	from := time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 0", time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=Europe/Berlin 0 12 * * *", time.Date(2024, 1, 31, 12, 0, 0, 0, berlin)},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.next(from); !got.Equal(test.want) {
+			t.Errorf("%s: got %s, want %s", test.expr, got, test.want)
+		}
+	}
+	for _, expr := range []string{"* * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := parseCron(expr, time.UTC); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	file := filepath.Join(t.TempDir(), "jobs.json")
+
+	s, err := bot.NewScheduler(SchedulerOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan JSONBody, 1)
+	s.Register("remind", func(bot *Bot, payload JSONBody) error {
+		done <- payload
+		return nil
+	})
+	if _, err := s.After(10*time.Millisecond, "remind", JSONBody{"text": "tea"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.After(time.Hour, RequestJob, JSONBody{"method": "deleteMessage", "chat_id": 1, "message_id": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Cron("@daily", "remind", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.After(time.Second, "unknown", nil); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+	s.Start()
+	select {
+	case payload := <-done:
+		if payload["text"] != "tea" {
+			t.Errorf("got payload %v", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+	s.Stop()
+
+	// pending jobs survive a restart; past one-off jobs run on start
+	s, err = bot.NewScheduler(SchedulerOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Kind != RequestJob || jobs[1].Cron != "@daily" {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	s.Cancel(jobs[1].ID)
+	s.jobs[jobs[0].ID].Next = time.Now()
+	s.Start()
+	time.Sleep(100 * time.Millisecond)
+	bot.Stop()
+	s.Stop()
+	calls := api.Calls()
+	if len(calls) != 1 || calls[0].Method != "deleteMessage" || calls[0].Params["message_id"] != float64(2) {
+		t.Errorf("unexpected calls %v", calls)
+	}
+	if len(s.Jobs()) != 0 {
+		t.Errorf("jobs left: %v", s.Jobs())
+	}
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	bot, _ := newTestBot(t, Settings{})
+	failed := make(chan error, 1)
+	s, err := bot.NewScheduler(SchedulerOptions{OnError: func(job Job, err error) {
+		failed <- err
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Register("boom", func(bot *Bot, payload JSONBody) error {
+		panic("job exploded")
+	})
+	if _, err := s.After(time.Millisecond, "boom", nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop()
+	select {
+	case err := <-failed:
+		if panicErr, ok := err.(*PanicError); !ok || panicErr.Value != "job exploded" {
+			t.Errorf("got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job panic was not reported")
+	}
+}
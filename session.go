@@ -0,0 +1,348 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SessionStore persists session data between updates.
+// Get returns nil data and no error for unknown keys.
+type SessionStore interface {
+	Get(key string) (JSONBody, error)
+	Set(key string, data JSONBody) error
+	Delete(key string) error
+}
+
+// Session is the data kept for a chat or user between updates.
+// Values go through JSON, so numbers come back as float64.
+type Session struct {
+	Key  string
+	Data JSONBody
+
+	destroyed bool
+}
+
+// Get returns a session value.
+func (s *Session) Get(key string) interface{} {
+	return s.Data[key]
+}
+
+// Set sets a session value.
+func (s *Session) Set(key string, value interface{}) {
+	s.Data[key] = value
+}
+
+// Delete removes a session value.
+func (s *Session) Delete(key string) {
+	delete(s.Data, key)
+}
+
+// Destroy removes the session from the store once the handler returns.
+func (s *Session) Destroy() {
+	s.Data = JSONBody{}
+	s.destroyed = true
+}
+
+// SessionOptions configures the session middleware.
+type SessionOptions struct {
+	// Store keeps the sessions. Default: in-memory store without TTL
+	Store SessionStore
+
+	// Key selects the session of an update; an empty key skips the
+	// session. Default: SessionByChat
+	Key func(Update) string
+}
+
+// SessionByChat shares one session between all users of a chat.
+func SessionByChat(update Update) string {
+	if chatID := update.ChatID(); chatID != 0 {
+		return strconv.FormatInt(chatID, 10)
+	}
+	return ""
+}
+
+// SessionByUser shares one session between all chats of a user.
+func SessionByUser(update Update) string {
+	if userID := update.FromID(); userID != 0 {
+		return strconv.FormatInt(userID, 10)
+	}
+	return ""
+}
+
+// SessionByChatUser keeps one session per user in each chat.
+func SessionByChatUser(update Update) string {
+	return sceneKey(update)
+}
+
+// SessionMiddleware loads the session before the handler runs and saves it
+// afterwards. Updates sharing a session are handled one at a time, so the
+// load-modify-save cycle is atomic. Use Bot.Session to get the session.
+func SessionMiddleware(opts SessionOptions) MiddlewareFunc {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore(0)
+	}
+	if opts.Key == nil {
+		opts.Key = SessionByChat
+	}
+	locks := &keyedMutex{locks: make(map[string]*keyedLock)}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(context interface{}, bot *Bot, update Update) JSONBody {
+			key := opts.Key(update)
+			if key == "" {
+				return next(context, bot, update)
+			}
+			locks.Lock(key)
+			defer locks.Unlock(key)
+
+			data, err := opts.Store.Get(key)
+			if err != nil {
+				bot.logger.Warn("session: load", "key", key, "error", err)
+				return next(context, bot, update)
+			}
+			if data == nil {
+				data = JSONBody{}
+			}
+			session := &Session{Key: key, Data: data}
+
+			id := updateIdentity(update)
+			bot.sessionMutex.Lock()
+			bot.sessions[id] = session
+			bot.sessionMutex.Unlock()
+			defer func() {
+				bot.sessionMutex.Lock()
+				delete(bot.sessions, id)
+				bot.sessionMutex.Unlock()
+			}()
+
+			res := next(context, bot, update)
+
+			if session.destroyed && len(session.Data) == 0 {
+				err = opts.Store.Delete(key)
+			} else {
+				err = opts.Store.Set(key, session.Data)
+			}
+			if err != nil {
+				bot.logger.Warn("session: save", "key", key, "error", err)
+			}
+			return res
+		}
+	}
+}
+
+// UseSession adds the session middleware to all handlers.
+func (bot *Bot) UseSession(opts SessionOptions) {
+	bot.Use(SessionMiddleware(opts))
+}
+
+// Session returns the session loaded for update by the session
+// middleware, or nil outside of it.
+func (bot *Bot) Session(update Update) *Session {
+	bot.sessionMutex.Lock()
+	defer bot.sessionMutex.Unlock()
+	return bot.sessions[updateIdentity(update)]
+}
+
+// updateIdentity identifies an update while it is handled.
+func updateIdentity(update Update) string {
+	if id := update.Get("update_id").Int(); id != 0 {
+		return strconv.FormatInt(id, 10)
+	}
+	return update.Raw
+}
+
+// keyedMutex serializes work per key.
+type keyedMutex struct {
+	mutex sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (k *keyedMutex) Lock(key string) {
+	k.mutex.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mutex.Unlock()
+	lock.Lock()
+}
+
+func (k *keyedMutex) Unlock(key string) {
+	k.mutex.Lock()
+	lock := k.locks[key]
+	lock.refs--
+	if lock.refs == 0 {
+		delete(k.locks, key)
+	}
+	k.mutex.Unlock()
+	lock.Unlock()
+}
+
+// MemoryStore is an in-memory SessionStore whose entries expire
+// after TTL without writes. A zero TTL keeps entries forever.
+type MemoryStore struct {
+	TTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]memoryEntry
+	swept   time.Time
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore creates an in-memory store.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		TTL:     ttl,
+		entries: make(map[string]memoryEntry),
+		swept:   time.Now(),
+	}
+}
+
+// Get implements SessionStore.
+func (s *MemoryStore) Get(key string) (JSONBody, error) {
+	s.mutex.Lock()
+	entry, ok := s.entries[key]
+	if ok && s.expired(entry, time.Now()) {
+		delete(s.entries, key)
+		ok = false
+	}
+	s.mutex.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	data := JSONBody{}
+	if err := json.Unmarshal(entry.data, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Set implements SessionStore.
+func (s *MemoryStore) Set(key string, data JSONBody) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries[key] = memoryEntry{data: raw, expires: now.Add(s.TTL)}
+	if s.TTL > 0 && now.Sub(s.swept) > s.TTL {
+		for k, entry := range s.entries {
+			if s.expired(entry, now) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+	return nil
+}
+
+// Delete implements SessionStore.
+func (s *MemoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	delete(s.entries, key)
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) expired(entry memoryEntry, now time.Time) bool {
+	return s.TTL > 0 && now.After(entry.expires)
+}
+
+// FileStore is a SessionStore keeping one JSON file per session in a
+// directory. Files are replaced atomically on every write.
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore creates a file store, creating dir if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// Get implements SessionStore.
+func (s *FileStore) Get(key string) (JSONBody, error) {
+	raw, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := JSONBody{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("session %s: %s", key, err)
+	}
+	return data, nil
+}
+
+// Set implements SessionStore.
+func (s *FileStore) Set(key string, data JSONBody) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(key), raw)
+}
+
+// Delete implements SessionStore.
+func (s *FileStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, url.QueryEscape(key)+".json")
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package easytgbot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "easytgbot-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := map[string]SessionStore{
+		"memory": NewMemoryStore(time.Hour),
+	}
+	if stores["file"], err = NewFileStore(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range stores {
+		bot, _ := New("token", Settings{})
+		bot.UseSession(SessionOptions{Store: store, Key: SessionByUser})
+		bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+			session := bot.Session(update)
+			count, _ := session.Get("count").(float64)
+			if update.Get("message.text").String() == "reset" {
+				session.Destroy()
+				return JSONBody{"count": count}
+			}
+			session.Set("count", count+1)
+			return JSONBody{"count": count + 1}
+		})
+
+		for i, want := range []float64{1, 2, 3} {
+			res, _ := bot.ApplyHandlers(nil, NewUpdate(fmt.Sprintf(`{"update_id":%d,"message":{"from":{"id":7},"chat":{"id":7},"text":"hi"}}`, i+1)))
+			if res["count"] != want {
+				t.Errorf("%s: got %v, want %v", name, res["count"], want)
+			}
+		}
+		bot.ApplyHandlers(nil, NewUpdate(`{"update_id":9,"message":{"from":{"id":7},"chat":{"id":7},"text":"reset"}}`))
+		if data, _ := store.Get("7"); data != nil {
+			t.Errorf("%s: expected destroyed session, got %v", name, data)
+		}
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond)
+	store.Set("k", JSONBody{"a": 1})
+	time.Sleep(5 * time.Millisecond)
+	if data, _ := store.Get("k"); data != nil {
+		t.Errorf("expected expired entry, got %v", data)
+	}
+}
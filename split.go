@@ -0,0 +1,494 @@
+package easytgbot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/imroc/req"
+)
+
+// Telegram text limits in UTF-16 code units.
+const (
+	MessageLimit = 4096
+	CaptionLimit = 1024
+)
+
+// captionMethods are the methods whose caption is limited by CaptionLimit.
+var captionMethods = map[string]bool{
+	"sendPhoto":     true,
+	"sendVideo":     true,
+	"sendAnimation": true,
+	"sendAudio":     true,
+	"sendDocument":  true,
+	"sendVoice":     true,
+}
+
+// chunkFields are the parameters copied to every message of a split send.
+var chunkFields = []string{"chat_id", "message_thread_id", "business_connection_id", "disable_notification", "protect_content"}
+
+// TextChunk is a part of a split text with its entities.
+type TextChunk struct {
+	Text     string
+	Entities []JSONBody
+}
+
+// SplitText splits text into chunks of at most limit UTF-16 code units.
+// It breaks at paragraphs, then lines, then words, and never inside an
+// entity, an HTML tag or character reference, or a Markdown escape or
+// delimiter. Entity offsets are rebased on each chunk, and with a parse
+// mode the tags and Markdown delimiters open at a break are closed and
+// reopened in the next chunk, within the limit.
+// Only an entity longer than limit is cut.
+func SplitText(text string, entities []JSONBody, parseMode string, limit int) []TextChunk {
+	runes := []rune(text)
+	n := len(runes)
+	// pos[i] is the UTF-16 offset of rune i
+	pos := make([]int, n+1)
+	for i, r := range runes {
+		pos[i+1] = pos[i] + utf16RuneLen(r)
+	}
+	if limit <= 0 || pos[n] <= limit {
+		return []TextChunk{{Text: text, Entities: entities}}
+	}
+
+	// blocked[i] is true when breaking before rune i is not allowed
+	blocked := make([]bool, n+1)
+	inside := make([]bool, pos[n]+1)
+	for _, entity := range entities {
+		offset := jsonInt(entity["offset"])
+		end := offset + jsonInt(entity["length"])
+		for u := offset + 1; u < end && u <= pos[n]; u++ {
+			if u > 0 {
+				inside[u] = true
+			}
+		}
+	}
+	for i := range blocked {
+		blocked[i] = inside[pos[i]]
+	}
+	var tokens []formatToken
+	switch mode := strings.ToLower(parseMode); {
+	case mode == "html":
+		tokens = htmlTokens(runes, blocked)
+	case strings.HasPrefix(mode, "markdown"):
+		tokens = markdownTokens(runes, mode == "markdownv2", blocked)
+	}
+
+	var chunks []TextChunk
+	for start := 0; start < n; {
+		prefix := strings.Join(formatState(tokens, start), "")
+		budget := limit - utf16Len(prefix)
+		var (
+			cut    int
+			suffix string
+		)
+		// shrink the chunk until it fits with the reopened and
+		// closed delimiters
+		for {
+			end := start
+			for end < n && pos[end+1]-pos[start] <= budget {
+				end++
+			}
+			if end == start {
+				end++
+			}
+			cut = end
+			if end < n {
+				cut = breakPoint(runes, blocked, start, end)
+			}
+			suffix = closeFormat(formatState(tokens, cut))
+			over := utf16Len(prefix) + pos[cut] - pos[start] + utf16Len(suffix) - limit
+			if over <= 0 || cut == start+1 {
+				break
+			}
+			budget -= over
+		}
+		chunks = append(chunks, TextChunk{
+			Text:     prefix + string(runes[start:cut]) + suffix,
+			Entities: chunkEntities(entities, pos[start], pos[cut]),
+		})
+		start = cut
+	}
+	return chunks
+}
+
+// breakPoint returns the best place in (start, end] to break the text.
+func breakPoint(runes []rune, blocked []bool, start int, end int) int {
+	separators := []func(i int) bool{
+		func(i int) bool { return runes[i-1] == '\n' && i-2 >= start && runes[i-2] == '\n' },
+		func(i int) bool { return runes[i-1] == '\n' },
+		func(i int) bool { return runes[i-1] == ' ' || runes[i-1] == '\t' },
+		func(i int) bool { return true },
+	}
+	for _, separator := range separators {
+		for i := end; i > start; i-- {
+			if !blocked[i] && separator(i) {
+				return i
+			}
+		}
+	}
+	return end
+}
+
+// formatToken is an HTML tag or a Markdown delimiter at runes
+// [start, end), with the tags or delimiters open after it, outermost
+// first.
+type formatToken struct {
+	start int
+	end   int
+	open  []string
+}
+
+// formatState returns the tags or delimiters open before rune i.
+func formatState(tokens []formatToken, i int) []string {
+	var open []string
+	for _, token := range tokens {
+		if token.end > i {
+			break
+		}
+		open = token.open
+	}
+	return open
+}
+
+// closeFormat returns the closing tags or delimiters for open,
+// innermost first.
+func closeFormat(open []string) string {
+	var res strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		res.WriteString(formatCloser(open[i]))
+	}
+	return res.String()
+}
+
+// formatCloser returns the closing tag or delimiter of opener.
+func formatCloser(opener string) string {
+	switch {
+	case strings.HasPrefix(opener, "<"):
+		name := strings.TrimSuffix(strings.Fields(opener[1:])[0], ">")
+		return "</" + name + ">"
+	case strings.HasPrefix(opener, "```"):
+		return "```"
+	}
+	return opener
+}
+
+// addToken records a token, blocking breaks inside it, after an
+// opening one and before a closing one.
+func addToken(tokens []formatToken, blocked []bool, start int, end int, open []string, opening bool) []formatToken {
+	for k := start + 1; k < end; k++ {
+		blocked[k] = true
+	}
+	if opening {
+		blocked[end] = true
+	} else {
+		blocked[start] = true
+	}
+	return append(tokens, formatToken{start: start, end: end, open: open})
+}
+
+// htmlTokens returns the tags of HTML text and blocks breaks inside
+// them and inside character references.
+func htmlTokens(runes []rune, blocked []bool) []formatToken {
+	var (
+		tokens []formatToken
+		open   []string
+	)
+	for i := 0; i < len(runes); i++ {
+		var closer rune
+		switch runes[i] {
+		case '<':
+			closer = '>'
+		case '&':
+			closer = ';'
+		default:
+			continue
+		}
+		for j := i + 1; j < len(runes); j++ {
+			if runes[j] != closer {
+				continue
+			}
+			if closer == ';' {
+				for k := i + 1; k <= j; k++ {
+					blocked[k] = true
+				}
+			} else if tag := string(runes[i : j+1]); strings.HasPrefix(tag, "</") {
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+				tokens = addToken(tokens, blocked, i, j+1, open, false)
+			} else {
+				open = append(open[:len(open):len(open)], tag)
+				tokens = addToken(tokens, blocked, i, j+1, open, true)
+			}
+			i = j
+			break
+		}
+	}
+	return tokens
+}
+
+// markdownTokens returns the delimiters of Markdown or MarkdownV2 text
+// and blocks breaks inside them and after escapes.
+func markdownTokens(runes []rune, v2 bool, blocked []bool) []formatToken {
+	var (
+		tokens []formatToken
+		open   []string
+	)
+	at := func(i int, delimiter string) bool {
+		end := i + len(delimiter)
+		return end <= len(runes) && string(runes[i:end]) == delimiter
+	}
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			blocked[i+1] = true
+			i++
+			continue
+		}
+		code := len(open) > 0 && strings.HasPrefix(open[len(open)-1], "`")
+		var delimiter string
+		switch {
+		case at(i, "```"):
+			delimiter = "```"
+		case runes[i] == '`':
+			delimiter = "`"
+		case code:
+			continue
+		case v2 && (at(i, "__") || at(i, "||")):
+			delimiter = string(runes[i : i+2])
+		case runes[i] == '*' || runes[i] == '_' || v2 && runes[i] == '~':
+			delimiter = string(runes[i])
+		default:
+			continue
+		}
+		end := i + len(delimiter)
+
+		top := -1
+		for k := len(open) - 1; k >= 0; k-- {
+			if formatCloser(open[k]) == delimiter {
+				top = k
+				break
+			}
+		}
+		switch {
+		case top >= 0:
+			open = append(open[:top:top], open[top+1:]...)
+			tokens = addToken(tokens, blocked, i, end, open, false)
+		case code:
+			// literal inside code
+			continue
+		default:
+			if delimiter == "```" {
+				// the language of a pre block ends at the line break
+				j := end
+				for j < len(runes) && runes[j] != '\n' && runes[j] != ' ' && runes[j] != '`' {
+					j++
+				}
+				if j > end && j < len(runes) && runes[j] == '\n' {
+					end = j + 1
+				}
+			}
+			open = append(open[:len(open):len(open)], string(runes[i:end]))
+			tokens = addToken(tokens, blocked, i, end, open, true)
+		}
+		i = end - 1
+	}
+	return tokens
+}
+
+// chunkEntities returns the entities overlapping [from, to) relative to from.
+func chunkEntities(entities []JSONBody, from int, to int) []JSONBody {
+	var res []JSONBody
+	for _, entity := range entities {
+		start := jsonInt(entity["offset"])
+		end := start + jsonInt(entity["length"])
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+		if start >= end {
+			continue
+		}
+		chunk := mergeJSON(JSONBody{}, entity)
+		chunk["offset"] = start - from
+		chunk["length"] = end - start
+		res = append(res, chunk)
+	}
+	return res
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16RuneLen(r)
+	}
+	return n
+}
+
+// jsonInt converts a JSON number to int.
+func jsonInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	}
+	return 0
+}
+
+// entityList converts an entities parameter to a list of JSONBody.
+func entityList(v interface{}) []JSONBody {
+	if v == nil {
+		return nil
+	}
+	if entities, ok := v.([]JSONBody); ok {
+		return entities
+	}
+	var entities []JSONBody
+	data, err := json.Marshal(v)
+	if err == nil {
+		json.Unmarshal(data, &entities)
+	}
+	return entities
+}
+
+// oversized reports whether body sends a text or caption over the limits.
+func oversized(endpoint string, params JSONBody) bool {
+	if endpoint == "sendMessage" {
+		text, _ := params["text"].(string)
+		return utf16Len(text) > MessageLimit
+	}
+	if captionMethods[endpoint] {
+		caption, _ := params["caption"].(string)
+		return utf16Len(caption) > CaptionLimit
+	}
+	return false
+}
+
+// splitRequest sends an oversized text or caption in several messages
+// with send, or as a text file above SplitDocumentThreshold chunks. It
+// reports whether the request was handled.
+func (bot *Bot) splitRequest(endpoint string, params JSONBody, send func(string, JSONBody) (Update, error)) (Update, bool, error) {
+	if !oversized(endpoint, params) {
+		return Update{}, false, nil
+	}
+	parseMode, _ := params["parse_mode"].(string)
+
+	if endpoint == "sendMessage" {
+		text := params["text"].(string)
+		chunks := SplitText(text, entityList(params["entities"]), parseMode, MessageLimit)
+		if bot.SplitDocumentThreshold > 0 && len(chunks) > bot.SplitDocumentThreshold {
+			document := chunkParams(params)
+			document["document"] = req.FileUpload{
+				File:      ioutil.NopCloser(strings.NewReader(text)),
+				FieldName: "document",
+				FileName:  "message.txt",
+			}
+			for _, key := range []string{"reply_to_message_id", "reply_parameters", "reply_markup"} {
+				if value, ok := params[key]; ok {
+					document[key] = value
+				}
+			}
+			res, err := send("sendDocument", document)
+			return res, true, err
+		}
+
+		var res Update
+		for i, chunk := range chunks {
+			body := mergeJSON(JSONBody{}, params)
+			body["text"] = chunk.Text
+			delete(body, "entities")
+			if chunk.Entities != nil {
+				body["entities"] = chunk.Entities
+			}
+			if i > 0 {
+				delete(body, "reply_to_message_id")
+				delete(body, "reply_parameters")
+			}
+			if i < len(chunks)-1 {
+				delete(body, "reply_markup")
+			}
+			var err error
+			if res, err = send(endpoint, body); err != nil {
+				return res, true, err
+			}
+		}
+		return res, true, nil
+	}
+
+	caption := params["caption"].(string)
+	chunks := SplitText(caption, entityList(params["caption_entities"]), parseMode, CaptionLimit)
+	media := mergeJSON(JSONBody{}, params)
+	media["caption"] = chunks[0].Text
+	delete(media, "caption_entities")
+	if chunks[0].Entities != nil {
+		media["caption_entities"] = chunks[0].Entities
+	}
+	res, err := send(endpoint, media)
+	if err != nil {
+		return res, true, err
+	}
+	for _, chunk := range chunks[1:] {
+		body := chunkParams(params)
+		body["text"] = chunk.Text
+		if parseMode != "" {
+			body["parse_mode"] = parseMode
+		}
+		if chunk.Entities != nil {
+			body["entities"] = chunk.Entities
+		}
+		if _, err := send("sendMessage", body); err != nil {
+			return res, true, err
+		}
+	}
+	return res, true, nil
+}
+
+// chunkParams copies the chunkFields of params.
+func chunkParams(params JSONBody) JSONBody {
+	body := JSONBody{}
+	for _, key := range chunkFields {
+		if value, ok := params[key]; ok {
+			body[key] = value
+		}
+	}
+	return body
+}
+
+// hasFileUpload reports whether params need a multipart request.
+func hasFileUpload(params JSONBody) bool {
+	for _, value := range params {
+		if _, ok := value.(req.FileUpload); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMethod separates the method of a handler result from its params.
+func splitMethod(body JSONBody) (string, JSONBody) {
+	method, _ := body["method"].(string)
+	params := JSONBody{}
+	for k, v := range body {
+		if k != "method" {
+			params[k] = v
+		}
+	}
+	return method, params
+}
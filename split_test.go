@@ -0,0 +1,123 @@
+package easytgbot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitText(t *testing.T) {
+	text := strings.Repeat("a", 8) + "\n\n" + strings.Repeat("b", 8) + " " + strings.Repeat("c", 5)
+	chunks := SplitText(text, nil, "", 12)
+	joined := ""
+	for _, chunk := range chunks {
+		if utf16Len(chunk.Text) > 12 {
+			t.Errorf("chunk %q is too long", chunk.Text)
+		}
+		joined += chunk.Text
+	}
+	if joined != text {
+		t.Errorf("chunks %q do not add up to the text", joined)
+	}
+	if chunks[0].Text != strings.Repeat("a", 8)+"\n\n" {
+		t.Errorf("expected a paragraph break, got %q", chunks[0].Text)
+	}
+}
+
+func TestSplitTextEntities(t *testing.T) {
+	// "😀 hello world": the emoji takes two UTF-16 code units
+	text := "😀 hello world"
+	entities := []JSONBody{{"type": "bold", "offset": 3, "length": 5}, {"type": "italic", "offset": 9, "length": 5}}
+	chunks := SplitText(text, entities, "", 6)
+	if chunks[0].Text != "😀 " || chunks[1].Text != "hello " {
+		t.Fatalf("unexpected chunks %+v", chunks)
+	}
+	if e := chunks[1].Entities[0]; e["offset"] != 0 || e["length"] != 5 || e["type"] != "bold" {
+		t.Errorf("entity was not rebased: %v", e)
+	}
+	if e := chunks[2].Entities[0]; e["offset"] != 0 || e["length"] != 5 || e["type"] != "italic" {
+		t.Errorf("entity was not rebased: %v", e)
+	}
+}
+
+func TestSplitTextMarkup(t *testing.T) {
+	chunks := SplitText("<b>one two &amp; three</b>", nil, "HTML", 14)
+	for _, chunk := range chunks {
+		if utf16Len(chunk.Text) > 14 {
+			t.Errorf("chunk %q is too long", chunk.Text)
+		}
+		if strings.Count(chunk.Text, "<b>") != strings.Count(chunk.Text, "</b>") {
+			t.Errorf("unbalanced chunk %q", chunk.Text)
+		}
+		if strings.Contains(chunk.Text, "&") && !strings.Contains(chunk.Text, "&amp;") {
+			t.Errorf("character reference was split: %q", chunk.Text)
+		}
+	}
+
+	chunks = SplitText(`abcd\*efgh`, nil, "MarkdownV2", 5)
+	if chunks[0].Text != "abcd" {
+		t.Errorf("escape was split: %+v", chunks)
+	}
+
+	cases := []struct {
+		text, parseMode string
+		want            []string
+	}{
+		{"*bold words* and _italic_", "Markdown", []string{"*bold *", "*words* and ", "_italic_"}},
+		{"__under line__ ||spoiler text||", "MarkdownV2", []string{"__under __", "__line__ ", "||spoiler ||", "||text||"}},
+		{"`a * b c d e` f", "MarkdownV2", []string{"`a * b c d `", "`e` f"}},
+	}
+	for _, c := range cases {
+		chunks := SplitText(c.text, nil, c.parseMode, 12)
+		got := []string{}
+		for _, chunk := range chunks {
+			got = append(got, chunk.Text)
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%q: got %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestSendLongMessage(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	text := strings.Repeat("word ", 2000)
+	if _, err := bot.SendMessage(7, text, JSONBody{"reply_markup": JSONBody{"inline_keyboard": []interface{}{}}}); err != nil {
+		t.Fatal(err)
+	}
+	calls := api.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("got %d calls, want 3", len(calls))
+	}
+	for i, call := range calls {
+		_, markup := call.Params["reply_markup"]
+		if markup != (i == len(calls)-1) {
+			t.Errorf("chunk %d: reply markup %v", i, markup)
+		}
+	}
+
+	bot.SplitDocumentThreshold = 2
+	bot.SendMessage(7, text, nil)
+	if call := api.Calls()[3]; call.Method != "sendDocument" || call.Params["document"] != "file" {
+		t.Errorf("expected a document, got %+v", call)
+	}
+}
+
+func TestSplitHandlerResult(t *testing.T) {
+	bot, api := newTestBot(t, Settings{})
+	bot.Handle("text", func(context interface{}, bot *Bot, update Update) JSONBody {
+		return update.SendMessage(strings.Repeat("x", MessageLimit+1), nil)
+	})
+	res, err := bot.ApplyHandlers(nil, NewUpdate(`{"message":{"message_id":1,"chat":{"id":7},"text":"hi"}}`))
+	if err != nil || len(res) != 0 {
+		t.Errorf("expected an empty webhook reply, got %v (%v)", res, err)
+	}
+	if calls := api.Calls(); len(calls) != 2 {
+		t.Errorf("got %d calls, want 2", len(calls))
+	}
+
+	bot.SendPhoto(7, "file", JSONBody{"caption": strings.Repeat("y", CaptionLimit+10)})
+	calls := api.Calls()[2:]
+	if len(calls) != 2 || calls[0].Method != "sendPhoto" || calls[1].Method != "sendMessage" {
+		t.Errorf("unexpected caption calls %+v", calls)
+	}
+}
@@ -1,8 +1,8 @@
 package easytgbot
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 	"unicode/utf8"
 
@@ -10,11 +10,21 @@ import (
 )
 
 // MessageNodes is message node name
-var MessageNodes = []string{"message", "edited_message", "channel_post", "edited_channel_post", "my_chat_member", "chat_member"}
+//
+// my_chat_member and chat_member are no longer message nodes, since
+// they hold no message: Message fails for them. Code reading them as
+// messages should use Chat, From or ChatMemberUpdated, see ChatNodes.
+var MessageNodes = []string{"message", "edited_message", "channel_post", "edited_channel_post"}
+
+// ChatNodes are update nodes with a chat and a sender but no message
+var ChatNodes = []string{"my_chat_member", "chat_member", "chat_join_request"}
 
 // MessageQueryNodes is message query node name
 var MessageQueryNodes = []string{"callback_query", "inline_query", "shipping_query", "pre_checkout_query", "chosen_inline_result"}
 
+// UpdateTypes are update types without a message, routed by GetType
+var UpdateTypes = []string{"inline_query", "chosen_inline_result", "shipping_query", "pre_checkout_query", "poll_answer", "chat_join_request"}
+
 // NewUpdate is create update instance
 func NewUpdate(data string) Update {
 	return Update{gjson.Parse(data)}
@@ -68,6 +78,11 @@ func (update Update) GetType() string {
 			return key
 		}
 	}
+	for _, key := range UpdateTypes {
+		if update.Get(key).Exists() {
+			return key
+		}
+	}
 	return "unknown"
 }
 
@@ -75,7 +90,7 @@ func (update Update) GetType() string {
 func (update Update) Command() (string, string) {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("bot command: %s\n", err)
+			defaultLogger.Error("bot command", "error", err)
 		}
 	}()
 	message, err := update.Message()
@@ -158,6 +173,15 @@ func (update Update) Message() (Update, error) {
 func (update Update) Chat() (Update, error) {
 	message, err := update.Message()
 	if err != nil {
+		for _, t := range ChatNodes {
+			if chat := update.Get(t + ".chat"); chat.Exists() {
+				return chat, nil
+			}
+		}
+		// anonymous poll answer on behalf of a chat
+		if voterChat := update.Get("poll_answer.voter_chat"); voterChat.Exists() {
+			return voterChat, nil
+		}
 		return Update{}, fmt.Errorf("chat is not found")
 	}
 	return message.Get("chat"), nil
@@ -165,6 +189,15 @@ func (update Update) Chat() (Update, error) {
 
 // From get update
 func (update Update) From() (Update, error) {
+	if user := update.Get("poll_answer.user"); user.Exists() {
+		return user, nil
+	}
+
+	for _, t := range ChatNodes {
+		if from := update.Get(t + ".from"); from.Exists() {
+			return from, nil
+		}
+	}
 
 	for _, t := range MessageQueryNodes {
 		callbackQuery := update.Get(t)
@@ -181,6 +214,24 @@ func (update Update) From() (Update, error) {
 	return Update{}, fmt.Errorf("from is not found")
 }
 
+// ChatID returns the id of the chat the update belongs to, or 0.
+func (update Update) ChatID() int64 {
+	chat, err := update.Chat()
+	if err != nil {
+		return 0
+	}
+	return chat.Get("id").Int()
+}
+
+// FromID returns the id of the user who sent the update, or 0.
+func (update Update) FromID() int64 {
+	from, err := update.From()
+	if err != nil {
+		return 0
+	}
+	return from.Get("id").Int()
+}
+
 // SendMessage is send message
 func (update Update) SendMessage(text string, extra JSONBody) JSONBody {
 	chat, _ := update.Chat()
@@ -226,6 +277,16 @@ func (update Update) Reply(text string, extra JSONBody) JSONBody {
 	return result
 }
 
+// ReplyT replies with the translation of key for the sender
+func (update Update) ReplyT(i18n *I18n, key string, args JSONBody, extra JSONBody) JSONBody {
+	return update.Reply(i18n.T(update, key, args), extra)
+}
+
+// SendMessageT sends the translation of key for the sender
+func (update Update) SendMessageT(i18n *I18n, key string, args JSONBody, extra JSONBody) JSONBody {
+	return update.SendMessage(i18n.T(update, key, args), extra)
+}
+
 // EditMessageText edit message
 func (update Update) EditMessageText(text string, extra JSONBody) JSONBody {
 	message, _ := update.Message()
@@ -298,6 +359,16 @@ func (update Update) SendMediaGroup(media []JSONBody, extra JSONBody) JSONBody {
 	return result
 }
 
+// toJSONBody converts a struct with json tags to a JSONBody
+func toJSONBody(v interface{}) JSONBody {
+	body := JSONBody{}
+	data, err := json.Marshal(v)
+	if err == nil {
+		json.Unmarshal(data, &body)
+	}
+	return body
+}
+
 // mergeJSON merge json body
 func mergeJSON(map1 JSONBody, map2 JSONBody) JSONBody {
 	for k, v := range map2 {